@@ -2,10 +2,12 @@ package builder
 
 import (
 	"bytes"
+	"encoding/json"
 	"os"
 	"testing"
 
 	output "github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+	"github.com/konveyor/tackle2-hub/shared/api"
 	"github.com/onsi/gomega"
 	"gopkg.in/yaml.v2"
 )
@@ -419,3 +421,437 @@ sha: "0x003"
 
 	g.Expect(expected).To(gomega.Equal(bfr.String()))
 }
+
+func TestFacts(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	mandatory := output.Mandatory
+	optional := output.Optional
+	effort := 3
+	report := []output.RuleSet{
+		{
+			Name: "RULESET-A",
+			Violations: map[string]output.Violation{
+				"rule-001": {
+					Category: &mandatory,
+					Effort:   &effort,
+					Incidents: []output.Incident{
+						{URI: "file:///path"},
+						{URI: "file:///path2"},
+					},
+				},
+				"rule-002": {
+					Category: &optional,
+					Effort:   &effort,
+					Incidents: []output.Incident{
+						{URI: "file:///path"},
+					},
+				},
+			},
+		},
+		{
+			Name: "RULESET-B",
+			Tags: []string{
+				"Language=Java",
+				"Language=Java",
+				"Framework=Spring",
+				"Docker",
+			},
+		},
+		{
+			Name: "RULESET-C",
+		},
+	}
+	builder, err := NewInsights(report)
+	g.Expect(err).To(gomega.BeNil())
+	builder.WithDeps(&Deps{
+		input: []output.DepsFlatItem{
+			{
+				Provider: "java",
+				Dependencies: []*output.Dep{
+					{Name: "dep001"},
+					{Name: "dep002"},
+				},
+			},
+		},
+	})
+	builder.WithCapabilities("java", []string{"referenced", "dependency"})
+	facts := builder.Facts()
+	g.Expect(facts[FactTechnologies]).To(gomega.Equal(
+		map[string][]string{
+			"Language":    {"Java"},
+			"Framework":   {"Spring"},
+			Uncategorized: {"Docker"},
+		}))
+	g.Expect(facts[FactCapabilities]).To(gomega.Equal(
+		map[string][]string{
+			"java": {"dependency", "referenced"},
+		}))
+	g.Expect(facts[FactDependencies]).To(gomega.Equal(
+		map[string]int{
+			"java": 2,
+		}))
+	g.Expect(facts[FactEffort]).To(gomega.Equal(
+		map[string]int{
+			"total":     9,
+			"mandatory": 6,
+			"optional":  3,
+			"potential": 0,
+		}))
+	g.Expect(facts[FactRuleSets]).To(gomega.Equal(
+		[]string{
+			"RULESET-A",
+			"RULESET-B",
+		}))
+}
+
+func TestTagEvidence(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	line := 7
+	report := []output.RuleSet{
+		{
+			Name: "RULESET-A",
+			Tags: []string{"Language=Java"},
+			Insights: map[string]output.Violation{
+				"rule-001": {
+					Labels: []string{
+						"konveyor.io/include=always",
+						"tag=Language=Java",
+						"tag=Language=Java",
+					},
+					Incidents: []output.Incident{
+						{URI: "file:///path", LineNumber: &line},
+						{URI: "file:///path2"},
+					},
+				},
+				"rule-002": {
+					Incidents: []output.Incident{
+						{URI: "file:///path"},
+					},
+				},
+			},
+		},
+	}
+	builder, err := NewInsights(report)
+	g.Expect(err).To(gomega.BeNil())
+	evidence := Evidence{}
+	builder.WithVisitors(&evidence)
+	builder.Visit()
+	g.Expect(evidence.Tags).To(gomega.Equal(
+		map[string][]TagEvidence{
+			"Language=Java": {
+				{RuleSet: "RULESET-A", Rule: "rule-001", File: "/path", Line: 7},
+				{RuleSet: "RULESET-A", Rule: "rule-001", File: "/path2"},
+			},
+		}))
+	v := report[0].Insights["rule-001"]
+	g.Expect(v.Incidents).To(gomega.BeNil())
+	v.Incidents = []output.Incident{{URI: "file:///path"}, {URI: "file:///path2"}}
+	saved := EvidenceLimit
+	EvidenceLimit = 1
+	defer func() {
+		EvidenceLimit = saved
+	}()
+	evidence = Evidence{}
+	evidence.Visit("RULESET-A", "rule-001", &v, false)
+	g.Expect(len(evidence.Tags["Language=Java"])).To(gomega.Equal(1))
+}
+
+func TestMerge(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	mandatory := output.Mandatory
+	effort := 1
+	report := []output.RuleSet{
+		{
+			Name: "RULESET-A",
+			Violations: map[string]output.Violation{
+				"rule-001": {
+					Category: &mandatory,
+					Effort:   &effort,
+					Incidents: []output.Incident{
+						{URI: "file:///changed", Message: "new"},
+					},
+				},
+				"rule-004": {
+					Incidents: []output.Incident{
+						{URI: "file:///changed", Message: "new"},
+					},
+				},
+			},
+		},
+	}
+	previous := []api.Insight{
+		{
+			RuleSet:  "RULESET-A",
+			Rule:     "rule-001",
+			Category: "mandatory",
+			Effort:   1,
+			Incidents: []api.Incident{
+				{File: "/changed", Message: "old"},
+				{File: "/unchanged", Message: "old", Line: 10},
+			},
+		},
+		{
+			RuleSet: "RULESET-A",
+			Rule:    "rule-004",
+			Incidents: []api.Incident{
+				{File: "/unchanged", Message: "old"},
+			},
+		},
+		{
+			RuleSet: "RULESET-B",
+			Rule:    "rule-002",
+			Labels:  []string{"tag=Language=Java"},
+			Incidents: []api.Incident{
+				{File: "/unchanged", Message: "old"},
+			},
+		},
+		{
+			RuleSet: "RULESET-B",
+			Rule:    "rule-005",
+			Labels:  []string{"tag=Language=Java"},
+			Incidents: []api.Incident{
+				{File: "/unchanged", Message: "old"},
+			},
+		},
+		{
+			RuleSet: "RULESET-B",
+			Rule:    "rule-003",
+			Labels:  []string{"tag=Language=Go"},
+			Incidents: []api.Incident{
+				{File: "/changed", Message: "old"},
+			},
+		},
+	}
+	builder, err := NewInsights(report)
+	g.Expect(err).To(gomega.BeNil())
+	builder.Merge(
+		previous,
+		func(p string) bool {
+			return p == "/changed"
+		})
+	g.Expect(len(builder.input)).To(gomega.Equal(2))
+	v := builder.input[0].Violations["rule-001"]
+	g.Expect(len(v.Incidents)).To(gomega.Equal(2))
+	g.Expect(v.Incidents[0].Message).To(gomega.Equal("new"))
+	g.Expect(string(v.Incidents[1].URI)).To(gomega.Equal("file:///unchanged"))
+	g.Expect(*v.Incidents[1].LineNumber).To(gomega.Equal(10))
+	g.Expect(builder.input[1].Name).To(gomega.Equal("RULESET-B"))
+	g.Expect(len(builder.input[1].Violations)).To(gomega.Equal(0))
+	g.Expect(len(builder.input[1].Insights)).To(gomega.Equal(2))
+	g.Expect(len(builder.input[1].Insights["rule-002"].Incidents)).To(gomega.Equal(1))
+	// classified as reported by this analysis.
+	g.Expect(len(builder.input[0].Violations["rule-004"].Incidents)).To(gomega.Equal(2))
+	g.Expect(len(builder.input[0].Insights)).To(gomega.Equal(0))
+	// tags derived from the merged insights.
+	g.Expect(builder.Tags()).To(gomega.Equal([]string{"Language=Java"}))
+}
+
+func TestSarif(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	t.Chdir(t.TempDir())
+	mandatory := output.Mandatory
+	effort := 5
+	line := 10
+	report := []output.RuleSet{
+		{
+			Name: "RULESET-A",
+			Violations: map[string]output.Violation{
+				"rule-001": {
+					Description: "rule-001 description.",
+					Category:    &mandatory,
+					Effort:      &effort,
+					Links: []output.Link{
+						{URL: "https://help"},
+					},
+					Incidents: []output.Incident{
+						{
+							URI:        "file:///path",
+							Message:    "rule-001 matched here.",
+							LineNumber: &line,
+							CodeSnip:   "code",
+						},
+					},
+				},
+			},
+			Insights: map[string]output.Violation{
+				"rule-002": {
+					Incidents: []output.Incident{
+						{
+							URI:     "file:///path2",
+							Message: "rule-002 matched here.",
+						},
+					},
+				},
+			},
+		},
+	}
+	insights, err := NewInsights(report)
+	g.Expect(err).To(gomega.BeNil())
+	sarif := Sarif{}
+	insights.WithVisitors(&sarif)
+	insights.Visit()
+	g.Expect(insights.Incidents()).To(gomega.Equal(map[string]int{"mandatory": 1}))
+	g.Expect(insights.Effort()["total"]).To(gomega.Equal(5))
+	err = sarif.Write()
+	g.Expect(err).To(gomega.BeNil())
+	b, err := os.ReadFile(sarif.Path)
+	g.Expect(err).To(gomega.BeNil())
+	log := SarifLog{}
+	err = json.Unmarshal(b, &log)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(log.Version).To(gomega.Equal(SarifVersion))
+	g.Expect(len(log.Runs)).To(gomega.Equal(1))
+	run := log.Runs[0]
+	g.Expect(len(run.Tool.Driver.Rules)).To(gomega.Equal(2))
+	g.Expect(run.Tool.Driver.Rules[0].ID).To(gomega.Equal("RULESET-A/rule-001"))
+	g.Expect(run.Tool.Driver.Rules[0].HelpURI).To(gomega.Equal("https://help"))
+	g.Expect(run.Tool.Driver.Rules[0].Properties["effort"]).To(gomega.Equal(float64(5)))
+	g.Expect(len(run.Results)).To(gomega.Equal(2))
+	result := run.Results[0]
+	g.Expect(result.Level).To(gomega.Equal(SarifError))
+	g.Expect(result.RuleIndex).To(gomega.Equal(0))
+	g.Expect(result.Locations[0].PhysicalLocation.ArtifactLocation.URI).To(gomega.Equal("file:///path"))
+	g.Expect(result.Locations[0].PhysicalLocation.Region.StartLine).To(gomega.Equal(10))
+	g.Expect(result.Properties["category"]).To(gomega.Equal("mandatory"))
+	result = run.Results[1]
+	g.Expect(result.Level).To(gomega.Equal(SarifNote))
+	g.Expect(result.RuleIndex).To(gomega.Equal(1))
+	g.Expect(result.Locations[0].PhysicalLocation.Region).To(gomega.BeNil())
+}
+
+func TestDiff(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	line := 10
+	moved := 20
+	report := []output.RuleSet{
+		{
+			Name: "RULESET-A",
+			Violations: map[string]output.Violation{
+				"rule-001": {
+					Incidents: []output.Incident{
+						{URI: "file:///path", CodeSnip: "A", LineNumber: &line, Message: "M"},
+						{URI: "file:///path", CodeSnip: "B", Message: "M"},
+					},
+				},
+			},
+		},
+	}
+	previous := &api.Analysis{
+		Resource: api.Resource{ID: 4},
+		Commit:   "abc",
+		Insights: []api.Insight{
+			{
+				RuleSet: "RULESET-A",
+				Rule:    "rule-001",
+				Incidents: []api.Incident{
+					{File: "/path", CodeSnip: "A", Line: 10, Message: "M"},
+					{File: "/path", CodeSnip: "C", Line: 8, Message: "M"},
+				},
+			},
+		},
+	}
+	insights, err := NewInsights(report)
+	g.Expect(err).To(gomega.BeNil())
+	diff := NewDiff(previous)
+	empty := NewDiff(nil)
+	insights.WithVisitors(diff, empty)
+	insights.Visit()
+	diff.Build()
+	g.Expect(diff.Report.Analysis).To(gomega.Equal(uint(4)))
+	g.Expect(diff.Report.Commit).To(gomega.Equal("abc"))
+	g.Expect(diff.Report.Summary).To(gomega.Equal(
+		DiffSummary{
+			New:       1,
+			Fixed:     1,
+			Unchanged: 1,
+		}))
+	g.Expect(len(diff.Report.Rules)).To(gomega.Equal(1))
+	r := diff.Report.Rules[0]
+	g.Expect(r.Unchanged[0].Line).To(gomega.Equal(10))
+	g.Expect(r.New[0].Line).To(gomega.Equal(0))
+	g.Expect(r.Fixed[0].Line).To(gomega.Equal(8))
+	// no previous.
+	empty.Build()
+	g.Expect(empty.Report.Summary).To(gomega.Equal(
+		DiffSummary{
+			New: 2,
+		}))
+	// identical snippets in one file.
+	report = []output.RuleSet{
+		{
+			Name: "RULESET-A",
+			Violations: map[string]output.Violation{
+				"rule-001": {
+					Incidents: []output.Incident{
+						{URI: "file:///path", CodeSnip: "A", LineNumber: &line, Message: "M"},
+						{URI: "file:///path", CodeSnip: "A", LineNumber: &moved, Message: "M"},
+						{URI: "file:///path", CodeSnip: "A", LineNumber: &moved, Message: "M"},
+					},
+				},
+			},
+		},
+	}
+	insights, err = NewInsights(report)
+	g.Expect(err).To(gomega.BeNil())
+	diff = NewDiff(previous)
+	insights.WithVisitors(diff)
+	insights.Visit()
+	diff.Build()
+	g.Expect(diff.Report.Summary).To(gomega.Equal(
+		DiffSummary{
+			New:       2,
+			Fixed:     1,
+			Unchanged: 1,
+		}))
+	r = diff.Report.Rules[0]
+	g.Expect(r.Unchanged[0].Line).To(gomega.Equal(10))
+	g.Expect(r.New[0].Line).To(gomega.Equal(20))
+	g.Expect(r.New[1].Line).To(gomega.Equal(20))
+}
+
+func TestManifest(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	t.Chdir(t.TempDir())
+
+	report := []output.RuleSet{
+		{
+			Name: "Test",
+			Violations: map[string]output.Violation{
+				"rule-001": {
+					Incidents: []output.Incident{
+						{
+							URI:     "file:///path",
+							Message: "rule-001 matched here.",
+						},
+					},
+				},
+			},
+		},
+	}
+	insights, err := NewInsights(report)
+	g.Expect(err).To(gomega.BeNil())
+	manifest := Manifest{
+		Analysis: api.Analysis{Commit: "abc"},
+		Insights: insights,
+		Deps:     &Deps{},
+	}
+	err = manifest.Write()
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(manifest.Path).To(gomega.Equal("manifest.yaml"))
+	plain, err := os.ReadFile(manifest.Path)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(string(plain)).To(gomega.ContainSubstring("rule: rule-001"))
+	// unavailable providers and provenance reported.
+	analysis := AnalysisReport{}
+	g.Expect(analysis.Empty()).To(gomega.BeTrue())
+	analysis.Unavailable = []string{"java"}
+	analysis.Provenance = &Provenance{
+		Repositories: []RuleRepository{{URL: "https://rules", Commit: "abc"}},
+	}
+	err = analysis.Write()
+	g.Expect(err).To(gomega.BeNil())
+	b, err := os.ReadFile(analysis.Path)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(string(b)).To(gomega.ContainSubstring("provenance:\n  repositories:\n  - url: https://rules\n    commit: abc"))
+	g.Expect(string(b)).To(gomega.ContainSubstring("unavailable:\n- java\n"))
+}
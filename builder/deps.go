@@ -21,6 +21,26 @@ type Deps struct {
 	input []output.DepsFlatItem
 }
 
+// Merge dependencies reported by a previous analysis.
+func (b *Deps) Merge(previous []api.TechDependency) {
+	for _, d := range previous {
+		b.input = append(
+			b.input,
+			output.DepsFlatItem{
+				Provider: d.Provider,
+				Dependencies: []*output.Dep{
+					{
+						Name:               d.Name,
+						Version:            d.Version,
+						Indirect:           d.Indirect,
+						ResolvedIdentifier: d.SHA,
+						Labels:             d.Labels,
+					},
+				},
+			})
+	}
+}
+
 // Write deps section.
 func (b *Deps) Write(writer io.Writer) (err error) {
 	wr := Writer{wrapped: writer}
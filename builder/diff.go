@@ -0,0 +1,216 @@
+package builder
+
+import (
+	"os"
+	"sort"
+
+	output "github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+	"github.com/konveyor/tackle2-hub/shared/api"
+	"gopkg.in/yaml.v2"
+	"k8s.io/utils/pointer"
+)
+
+// NewDiff returns the diff between the insights reported by
+// a previous analysis and the insights reported by this analysis.
+// The diff visits the insights (as they are walked) and Build()
+// must be called once the insights have been walked.
+func NewDiff(previous *api.Analysis) (d *Diff) {
+	d = &Diff{
+		before: make(map[DiffKey]int),
+		after:  make(map[DiffKey]int),
+	}
+	if previous == nil {
+		return
+	}
+	d.Report.Analysis = previous.ID
+	d.Report.Commit = previous.Commit
+	for _, insight := range previous.Insights {
+		for _, i := range insight.Incidents {
+			d.add(
+				d.before,
+				insight.RuleSet,
+				insight.Rule,
+				DiffIncident{
+					File:    i.File,
+					Line:    i.Line,
+					Message: i.Message,
+				})
+		}
+	}
+	return
+}
+
+// Diff of incidents reported by a previous analysis.
+// Incidents are keyed by ruleset, rule, file, line and message
+// and counted (multiset) so that identical incidents reported
+// more than once are each classified as:
+//   - new: not reported by the previous analysis.
+//   - fixed: only reported by the previous analysis.
+//   - unchanged: reported by both.
+type Diff struct {
+	Report DiffReport
+	Path   string
+	before map[DiffKey]int
+	after  map[DiffKey]int
+}
+
+// Visit records the incidents reported by the rule.
+func (d *Diff) Visit(ruleset, ruleid string, v *output.Violation, _ bool) {
+	for _, i := range v.Incidents {
+		d.add(
+			d.after,
+			ruleset,
+			ruleid,
+			DiffIncident{
+				File:    fileRef(i.URI),
+				Line:    pointer.IntDeref(i.LineNumber, 0),
+				Message: i.Message,
+			})
+	}
+}
+
+// add (counts) the incident.
+func (d *Diff) add(m map[DiffKey]int, ruleset, ruleid string, incident DiffIncident) {
+	key := DiffKey{
+		DiffRuleKey: DiffRuleKey{
+			RuleSet: ruleset,
+			Rule:    ruleid,
+		},
+		DiffIncident: incident,
+	}
+	m[key]++
+}
+
+// Write diff file.
+func (d *Diff) Write() (err error) {
+	d.Path = "diff.yaml"
+	file, err := os.Create(d.Path)
+	if err != nil {
+		return
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+	encoder := yaml.NewEncoder(file)
+	err = encoder.Encode(d.Report)
+	if err != nil {
+		return
+	}
+	err = encoder.Close()
+	return
+}
+
+// Build the report.
+func (d *Diff) Build() {
+	rules := make(map[DiffRuleKey]*DiffRule)
+	rule := func(key DiffRuleKey) (r *DiffRule) {
+		r, found := rules[key]
+		if !found {
+			r = &DiffRule{
+				RuleSet: key.RuleSet,
+				Rule:    key.Rule,
+			}
+			rules[key] = r
+		}
+		return
+	}
+	d.Report.Summary = DiffSummary{}
+	for key, n := range d.after {
+		r := rule(key.DiffRuleKey)
+		unchanged := min(n, d.before[key])
+		for i := 0; i < unchanged; i++ {
+			r.Unchanged = append(r.Unchanged, key.DiffIncident)
+			d.Report.Summary.Unchanged++
+		}
+		for i := unchanged; i < n; i++ {
+			r.New = append(r.New, key.DiffIncident)
+			d.Report.Summary.New++
+		}
+	}
+	for key, n := range d.before {
+		fixed := n - min(n, d.after[key])
+		if fixed == 0 {
+			continue
+		}
+		r := rule(key.DiffRuleKey)
+		for i := 0; i < fixed; i++ {
+			r.Fixed = append(r.Fixed, key.DiffIncident)
+			d.Report.Summary.Fixed++
+		}
+	}
+	d.Report.Rules = []DiffRule{}
+	for _, r := range rules {
+		r.sort()
+		d.Report.Rules = append(d.Report.Rules, *r)
+	}
+	sort.Slice(
+		d.Report.Rules,
+		func(i, j int) bool {
+			ri := d.Report.Rules[i]
+			rj := d.Report.Rules[j]
+			if ri.RuleSet != rj.RuleSet {
+				return ri.RuleSet < rj.RuleSet
+			}
+			return ri.Rule < rj.Rule
+		})
+}
+
+// DiffReport diff report.
+type DiffReport struct {
+	Analysis uint        `json:"analysis"`
+	Commit   string      `json:"commit,omitempty" yaml:",omitempty"`
+	Summary  DiffSummary `json:"summary"`
+	Rules    []DiffRule  `json:"rules"`
+}
+
+// DiffSummary incident counts.
+type DiffSummary struct {
+	New       int `json:"new"`
+	Fixed     int `json:"fixed"`
+	Unchanged int `json:"unchanged"`
+}
+
+// DiffRule incidents (by classification) for a rule.
+type DiffRule struct {
+	RuleSet   string         `json:"ruleset"`
+	Rule      string         `json:"rule"`
+	New       []DiffIncident `json:"new,omitempty" yaml:",omitempty"`
+	Fixed     []DiffIncident `json:"fixed,omitempty" yaml:",omitempty"`
+	Unchanged []DiffIncident `json:"unchanged,omitempty" yaml:",omitempty"`
+}
+
+// sort incidents.
+func (r *DiffRule) sort() {
+	for _, list := range [][]DiffIncident{r.New, r.Fixed, r.Unchanged} {
+		sort.Slice(
+			list,
+			func(i, j int) bool {
+				if list[i].File != list[j].File {
+					return list[i].File < list[j].File
+				}
+				if list[i].Line != list[j].Line {
+					return list[i].Line < list[j].Line
+				}
+				return list[i].Message < list[j].Message
+			})
+	}
+}
+
+// DiffIncident incident.
+type DiffIncident struct {
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+}
+
+// DiffRuleKey rule key.
+type DiffRuleKey struct {
+	RuleSet string
+	Rule    string
+}
+
+// DiffKey incident key.
+type DiffKey struct {
+	DiffRuleKey
+	DiffIncident
+}
@@ -0,0 +1,77 @@
+package builder
+
+import (
+	"sort"
+	"strings"
+
+	output "github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+	"k8s.io/utils/pointer"
+)
+
+// TagLabel prefix of labels added by the analyzer to the
+// insights reported by tagging rules.
+const TagLabel = "tag="
+
+// EvidenceLimit the maximum number of incidents
+// reported as evidence for each tag by rule.
+var EvidenceLimit = 10
+
+// TagEvidence an incident that produced a tag.
+type TagEvidence struct {
+	RuleSet string `yaml:"ruleSet"`
+	Rule    string `yaml:"rule"`
+	File    string `yaml:"file"`
+	Line    int    `yaml:"line,omitempty"`
+}
+
+// Evidence (visitor) collects the evidence (incidents) by tag.
+// The incidents are reported by (ruleset) rules with labeled (tag=)
+// insights. Limited to EvidenceLimit incidents for each tag by rule.
+type Evidence struct {
+	Tags map[string][]TagEvidence
+}
+
+// Visit collects the evidence reported by the rule.
+func (e *Evidence) Visit(ruleset, ruleid string, v *output.Violation, _ bool) {
+	if e.Tags == nil {
+		e.Tags = make(map[string][]TagEvidence)
+	}
+	for _, tag := range tagLabels(v.Labels) {
+		e.Tags[tag] = append(
+			e.Tags[tag],
+			e.evidence(ruleset, ruleid, v.Incidents)...)
+	}
+}
+
+// tagLabels returns the (unique) tags in the labels.
+func tagLabels(labels []string) (tags []string) {
+	history := make(map[string]bool)
+	for _, label := range labels {
+		tag, found := strings.CutPrefix(label, TagLabel)
+		if !found || tag == "" || history[tag] {
+			continue
+		}
+		history[tag] = true
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	return
+}
+
+// evidence returns the evidence for the rule incidents.
+func (e *Evidence) evidence(ruleset, ruleid string, incidents []output.Incident) (list []TagEvidence) {
+	for _, i := range incidents {
+		if len(list) == EvidenceLimit {
+			break
+		}
+		list = append(
+			list,
+			TagEvidence{
+				RuleSet: ruleset,
+				Rule:    ruleid,
+				File:    fileRef(i.URI),
+				Line:    pointer.IntDeref(i.LineNumber, 0),
+			})
+	}
+	return
+}
@@ -0,0 +1,152 @@
+package builder
+
+import (
+	"sort"
+	"strings"
+
+	output "github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+	"github.com/konveyor/tackle2-hub/shared/api"
+	"k8s.io/utils/pointer"
+)
+
+// Fact keys.
+const (
+	FactTechnologies = "technologies"
+	FactCapabilities = "capabilities"
+	FactDependencies = "dependencies"
+	FactEffort       = "effort"
+	FactRuleSets     = "ruleSets"
+)
+
+// FactModule incident fact key for the module.
+const FactModule = "module"
+
+// Uncategorized technology (tag) category.
+const Uncategorized = "Uncategorized"
+
+// WithDeps sets the dependencies used to build facts.
+func (b *Insights) WithDeps(deps *Deps) {
+	b.deps = deps
+}
+
+// WithCapabilities sets the capabilities reported by a provider.
+func (b *Insights) WithCapabilities(provider string, capabilities []string) {
+	if b.capabilities == nil {
+		b.capabilities = make(map[string][]string)
+	}
+	capabilities = append([]string{}, capabilities...)
+	sort.Strings(capabilities)
+	b.capabilities[provider] = capabilities
+}
+
+// Facts builds facts.
+// Facts are grouped by key:
+//   - technologies: tag category => tag names.
+//   - capabilities: provider => capability names.
+//   - dependencies: provider => dependency count.
+//   - effort: total and by category.
+//   - ruleSets: rulesets that matched.
+func (b *Insights) Facts() (facts api.Map) {
+	facts = api.Map{
+		FactTechnologies: b.technologies(),
+		FactCapabilities: b.providerCapabilities(),
+		FactDependencies: b.dependencies(),
+		FactEffort:       b.Effort(),
+		FactRuleSets:     b.ruleSets(),
+	}
+	return
+}
+
+// technologies returns detected technologies (tags) by category.
+func (b *Insights) technologies() (m map[string][]string) {
+	m = make(map[string][]string)
+	history := make(map[string]bool)
+	for _, tag := range b.Tags() {
+		if history[tag] {
+			continue
+		}
+		history[tag] = true
+		category := Uncategorized
+		name := tag
+		part := strings.SplitN(tag, "=", 2)
+		if len(part) == 2 && part[0] != "" && part[1] != "" {
+			category = part[0]
+			name = part[1]
+		}
+		m[category] = append(m[category], name)
+	}
+	for _, names := range m {
+		sort.Strings(names)
+	}
+	return
+}
+
+// providerCapabilities returns capabilities by provider.
+func (b *Insights) providerCapabilities() (m map[string][]string) {
+	m = make(map[string][]string)
+	for name, capabilities := range b.capabilities {
+		m[name] = capabilities
+	}
+	return
+}
+
+// dependencies returns the number of dependencies by provider.
+func (b *Insights) dependencies() (m map[string]int) {
+	m = make(map[string]int)
+	if b.deps == nil {
+		return
+	}
+	for _, p := range b.deps.input {
+		m[p.Provider] += len(p.Dependencies)
+	}
+	return
+}
+
+// Effort returns the effort total and the effort by category.
+// The effort is the rule effort multiplied by the incidents.
+func (b *Insights) Effort() (m map[string]int) {
+	m = map[string]int{
+		"total":                  0,
+		string(output.Mandatory): 0,
+		string(output.Optional):  0,
+		string(output.Potential): 0,
+	}
+	for _, ruleset := range b.input {
+		for ruleid, v := range ruleset.Violations {
+			effort := pointer.IntDeref(v.Effort, 0) * b.incidents(ruleset.Name, ruleid, &v)
+			m["total"] += effort
+			if v.Category != nil {
+				m[string(*v.Category)] += effort
+			}
+		}
+	}
+	return
+}
+
+// Incidents returns the number of (violation) incidents by category.
+func (b *Insights) Incidents() (m map[string]int) {
+	m = make(map[string]int)
+	for _, ruleset := range b.input {
+		for ruleid, v := range ruleset.Violations {
+			if v.Category != nil {
+				m[string(*v.Category)] += b.incidents(ruleset.Name, ruleid, &v)
+			}
+		}
+	}
+	return
+}
+
+// ruleSets returns the sorted names of rulesets that matched.
+func (b *Insights) ruleSets() (names []string) {
+	names = []string{}
+	for _, ruleset := range b.input {
+		matched := len(ruleset.Violations) > 0 ||
+			len(ruleset.Insights) > 0 ||
+			len(ruleset.Tags) > 0
+		if matched {
+			names = append(names, ruleset.Name)
+		}
+	}
+	sort.Strings(names)
+	return
+}
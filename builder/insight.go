@@ -5,18 +5,23 @@ import (
 	"fmt"
 	"io"
 	"net/url"
+	"reflect"
 	"sort"
+	"strings"
 
 	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
 	output "github.com/konveyor/analyzer-lsp/output/v1/konveyor"
 	hub "github.com/konveyor/tackle2-hub/shared/addon"
 	"github.com/konveyor/tackle2-hub/shared/api"
 	"go.lsp.dev/uri"
+	"gopkg.in/yaml.v2"
 	"k8s.io/utils/pointer"
 )
 
 var (
 	addon = hub.Addon
+	// incidentsAfter the (yaml) insight fields declared after the incidents.
+	incidentsAfter = fieldsAfter(api.Insight{}, "Incidents")
 )
 
 // NewInsights returns a new insights builder.
@@ -29,9 +34,21 @@ func NewInsights(o []konveyor.RuleSet) (b *Insights, err error) {
 
 // Insights builds insights and facts.
 type Insights struct {
-	ruleErr RuleError
-	facts   []api.Fact
-	input   []output.RuleSet
+	ruleErr      RuleError
+	input        []output.RuleSet
+	deps         *Deps
+	capabilities map[string][]string
+	modules      map[string]string
+	skipped      map[string]string
+	visitors     []Visitor
+	counts       map[string]int
+}
+
+// Visitor visits each insight (and incidents) as it is walked.
+type Visitor interface {
+	// Visit the insight reported by the (ruleset) rule.
+	// The violation is false for (informational) insights.
+	Visit(ruleset, ruleid string, v *output.Violation, violation bool)
 }
 
 // RuleError returns the rule error.
@@ -39,95 +56,218 @@ func (b *Insights) RuleError() (r *RuleError) {
 	for _, ruleset := range b.input {
 		b.ruleErr.Append(ruleset)
 	}
+	for ruleid, reason := range b.skipped {
+		b.ruleErr.Skip(ruleid, reason)
+	}
 	return &b.ruleErr
 }
 
+// WithSkipped sets the rules (ruleid => reason) skipped
+// and not reported by the analyzer.
+func (b *Insights) WithSkipped(skipped map[string]string) {
+	b.skipped = skipped
+}
+
 // Write insights section.
+// The insights are written rule set by rule set and the incidents
+// are encoded individually so that memory is bounded by the
+// largest incident rather than the largest insight.
+// The incidents are released once written (and visited).
 func (b *Insights) Write(writer io.Writer) (err error) {
 	wr := Writer{wrapped: writer}
-	b.ensureUnique()
 	wr.Write(api.BeginInsightsMarker)
 	wr.Write("\n")
-	for _, ruleset := range b.input {
-		for _, ruleid := range b.ruleIds(ruleset.Violations) {
-			v := ruleset.Violations[ruleid]
-			insight := api.Insight{
-				RuleSet:     ruleset.Name,
-				Rule:        ruleid,
-				Description: v.Description,
-				Labels:      v.Labels,
-			}
+	b.walk(func(ruleset, ruleid string, v *output.Violation, violation bool) {
+		if wr.Error() != nil {
+			return
+		}
+		insight := api.Insight{
+			RuleSet:     ruleset,
+			Rule:        ruleid,
+			Description: v.Description,
+			Labels:      v.Labels,
+		}
+		if violation {
 			if v.Category != nil {
 				insight.Category = string(*v.Category)
 			}
 			if v.Effort != nil {
 				insight.Effort = *v.Effort
 			}
-			insight.Links = []api.Link{}
-			for _, l := range v.Links {
-				insight.Links = append(
-					insight.Links,
-					api.Link{
-						URL:   l.URL,
-						Title: l.Title,
-					})
-			}
-			insight.Incidents = []api.Incident{}
-			for _, i := range v.Incidents {
-				incident := api.Incident{
-					File:     b.fileRef(i.URI),
-					Line:     pointer.IntDeref(i.LineNumber, 0),
-					Message:  i.Message,
-					CodeSnip: i.CodeSnip,
-					Facts:    i.Variables,
+		}
+		b.writeInsight(&wr, &insight, v)
+	})
+	wr.Write(api.EndInsightsMarker)
+	wr.Write("\n")
+	err = wr.Error()
+	return
+}
+
+// Visit the insights without writing them.
+// The incidents are released once visited.
+func (b *Insights) Visit() {
+	b.walk(func(string, string, *output.Violation, bool) {})
+}
+
+// WithVisitors adds visitors called for each insight (and
+// incidents) before the incidents are released.
+func (b *Insights) WithVisitors(visitors ...Visitor) {
+	b.visitors = append(b.visitors, visitors...)
+}
+
+// walk the insights rule set by rule set.
+// The function and visitors are called for each insight, then the
+// incidents are released (and counted) so that the memory is
+// reclaimed as the walk proceeds. The insights are walked once.
+func (b *Insights) walk(fn func(ruleset, ruleid string, v *output.Violation, violation bool)) {
+	b.ensureUnique()
+	if b.counts == nil {
+		b.counts = make(map[string]int)
+	}
+	for _, ruleset := range b.input {
+		collections := []map[string]output.Violation{
+			ruleset.Violations,
+			ruleset.Insights,
+		}
+		for n, violations := range collections {
+			violation := n == 0
+			for _, ruleid := range b.ruleIds(violations) {
+				v := violations[ruleid]
+				fn(ruleset.Name, ruleid, &v, violation)
+				for _, visitor := range b.visitors {
+					visitor.Visit(ruleset.Name, ruleid, &v, violation)
 				}
-				insight.Incidents = append(
-					insight.Incidents,
-					incident)
+				b.counts[ruleset.Name+"."+ruleid] = len(v.Incidents)
+				v.Incidents = nil
+				violations[ruleid] = v
 			}
-			wr.Encode(&insight)
 		}
-		for _, ruleid := range b.ruleIds(ruleset.Insights) {
-			v := ruleset.Insights[ruleid]
-			insight := api.Insight{
-				RuleSet:     ruleset.Name,
-				Rule:        ruleid,
-				Description: v.Description,
-				Labels:      v.Labels,
-			}
-			insight.Links = []api.Link{}
-			for _, l := range v.Links {
-				insight.Links = append(
-					insight.Links,
-					api.Link{
-						URL:   l.URL,
-						Title: l.Title,
-					})
-			}
-			insight.Incidents = []api.Incident{}
-			for _, i := range v.Incidents {
-				incident := api.Incident{
-					File:     b.fileRef(i.URI),
-					Line:     pointer.IntDeref(i.LineNumber, 0),
-					Message:  i.Message,
-					CodeSnip: i.CodeSnip,
-					Facts:    i.Variables,
-				}
-				insight.Incidents = append(
-					insight.Incidents,
-					incident)
+	}
+}
+
+// incidents returns the number of incidents reported by the rule.
+// The count is used after the incidents are released.
+func (b *Insights) incidents(ruleset, ruleid string, v *output.Violation) (n int) {
+	n = len(v.Incidents)
+	if n == 0 {
+		n = b.counts[ruleset+"."+ruleid]
+	}
+	return
+}
+
+// writeInsight writes an insight document.
+// The incidents are streamed into the document at the
+// position they would have been encoded.
+func (b *Insights) writeInsight(wr *Writer, insight *api.Insight, v *output.Violation) {
+	insight.Links = []api.Link{}
+	for _, l := range v.Links {
+		insight.Links = append(
+			insight.Links,
+			api.Link{
+				URL:   l.URL,
+				Title: l.Title,
+			})
+	}
+	head, tail, err := b.split(insight)
+	if err != nil {
+		wr.addError(err)
+		return
+	}
+	wr.Write("---\n")
+	wr.encode(head)
+	if len(v.Incidents) > 0 {
+		wr.Write("incidents:\n")
+	}
+	for _, i := range v.Incidents {
+		incident := api.Incident{
+			File:     fileRef(i.URI),
+			Line:     pointer.IntDeref(i.LineNumber, 0),
+			Message:  i.Message,
+			CodeSnip: i.CodeSnip,
+			Facts:    i.Variables,
+		}
+		b.tagModule(&incident)
+		wr.EncodeItem(&incident)
+	}
+	wr.encode(tail)
+}
+
+// split the encoded insight (without incidents) into the fields
+// declared before and after the incidents.
+func (b *Insights) split(insight *api.Insight) (head, tail yaml.MapSlice, err error) {
+	encoded, err := yaml.Marshal(insight)
+	if err != nil {
+		return
+	}
+	fields := yaml.MapSlice{}
+	err = yaml.Unmarshal(encoded, &fields)
+	if err != nil {
+		return
+	}
+	for _, field := range fields {
+		key, _ := field.Key.(string)
+		if incidentsAfter[key] {
+			tail = append(tail, field)
+		} else {
+			head = append(head, field)
+		}
+	}
+	return
+}
+
+// fieldsAfter returns the (yaml) names of the fields
+// declared after the named field.
+func fieldsAfter(object any, field string) (names map[string]bool) {
+	names = make(map[string]bool)
+	found := false
+	kind := reflect.TypeOf(object)
+	for i := 0; i < kind.NumField(); i++ {
+		f := kind.Field(i)
+		if found {
+			name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+			if name == "" {
+				name = strings.ToLower(f.Name)
 			}
-			wr.Encode(&insight)
+			names[name] = true
+		}
+		if f.Name == field {
+			found = true
 		}
 	}
-	wr.Write(api.EndInsightsMarker)
-	wr.Write("\n")
-	err = wr.Error()
 	return
 }
 
+// WithModules sets the modules (path => name) used to
+// tag each incident with the module it was reported in.
+func (b *Insights) WithModules(modules map[string]string) {
+	b.modules = modules
+}
+
+// tagModule tags the incident with the module (fact) that
+// contains the incident file. The longest (path) match wins.
+func (b *Insights) tagModule(incident *api.Incident) {
+	matched := ""
+	for p := range b.modules {
+		if len(p) <= len(matched) {
+			continue
+		}
+		if incident.File == p || strings.HasPrefix(incident.File, p+"/") {
+			matched = p
+		}
+	}
+	if matched == "" {
+		return
+	}
+	facts := api.Map{}
+	for k, v := range incident.Facts {
+		facts[k] = v
+	}
+	facts[FactModule] = b.modules[matched]
+	incident.Facts = facts
+}
+
 // fileRef returns the file (relative) path.
-func (b *Insights) fileRef(in uri.URI) (s string) {
+func fileRef(in uri.URI) (s string) {
 	s = string(in)
 	u, err := url.Parse(s)
 	if err == nil {
@@ -136,19 +276,21 @@ func (b *Insights) fileRef(in uri.URI) (s string) {
 	return
 }
 
-// Tags builds tags.
+// Tags builds (unique) tags.
 func (b *Insights) Tags() (tags []string) {
+	history := make(map[string]bool)
 	for _, r := range b.input {
-		tags = append(tags, r.Tags...)
+		for _, tag := range r.Tags {
+			if history[tag] {
+				continue
+			}
+			history[tag] = true
+			tags = append(tags, tag)
+		}
 	}
 	return
 }
 
-// Facts builds facts.
-func (b *Insights) Facts() (facts api.Map) {
-	return
-}
-
 // ensureUnique detect rules reporting both violation and insight.
 // Append (_) suffix to ruleid as needed.
 func (b *Insights) ensureUnique() {
@@ -185,7 +327,8 @@ func (b *Insights) ruleIds(m map[string]output.Violation) (ids []string) {
 
 // RuleError reported by the analyzer.
 type RuleError struct {
-	items map[string]string
+	items   map[string]string
+	skipped map[string]string
 }
 
 func (e *RuleError) Error() (s string) {
@@ -211,12 +354,20 @@ func (e *RuleError) Append(ruleset output.RuleSet) {
 	}
 }
 
+// Skip records a skipped rule.
+func (e *RuleError) Skip(ruleid, reason string) {
+	if e.skipped == nil {
+		e.skipped = make(map[string]string)
+	}
+	e.skipped[ruleid] = reason
+}
+
 func (e *RuleError) NotEmpty() (b bool) {
 	return len(e.items) > 0
 }
 
 func (e *RuleError) Report() {
-	if len(e.items) == 0 {
+	if len(e.items)+len(e.skipped) == 0 {
 		return
 	}
 	var errors []api.TaskError
@@ -228,5 +379,13 @@ func (e *RuleError) Report() {
 				Description: fmt.Sprintf("[Analyzer] %s: %s", ruleid, err),
 			})
 	}
+	for ruleid, reason := range e.skipped {
+		errors = append(
+			errors,
+			api.TaskError{
+				Severity:    "Warning",
+				Description: fmt.Sprintf("[Analyzer] %s: skipped. %s", ruleid, reason),
+			})
+	}
 	addon.Error(errors...)
 }
@@ -1,6 +1,8 @@
 package builder
 
 import (
+	"bufio"
+	"io"
 	"os"
 
 	"github.com/konveyor/tackle2-hub/shared/api"
@@ -16,6 +18,7 @@ type Manifest struct {
 }
 
 // Write manifest file.
+// The sections are streamed through a buffered writer.
 func (m *Manifest) Write() (err error) {
 	m.Path = "manifest.yaml"
 	file, err := os.Create(m.Path)
@@ -25,20 +28,35 @@ func (m *Manifest) Write() (err error) {
 	defer func() {
 		_ = file.Close()
 	}()
-	_, _ = file.Write([]byte(api.BeginMainMarker))
-	_, _ = file.Write([]byte{'\n'})
-	encoder := yaml.NewEncoder(file)
+	buffered := bufio.NewWriter(file)
+	err = m.write(buffered)
+	if err != nil {
+		return
+	}
+	err = buffered.Flush()
+	return
+}
+
+// write the manifest sections.
+func (m *Manifest) write(writer io.Writer) (err error) {
+	_, err = writer.Write([]byte(api.BeginMainMarker + "\n"))
+	if err != nil {
+		return
+	}
+	encoder := yaml.NewEncoder(writer)
 	err = encoder.Encode(m.Analysis)
 	if err != nil {
 		return
 	}
-	_, _ = file.Write([]byte(api.EndMainMarker))
-	_, _ = file.Write([]byte{'\n'})
-	err = m.Insights.Write(file)
+	_, err = writer.Write([]byte(api.EndMainMarker + "\n"))
+	if err != nil {
+		return
+	}
+	err = m.Insights.Write(writer)
 	if err != nil {
 		return
 	}
-	err = m.Deps.Write(file)
+	err = m.Deps.Write(writer)
 	if err != nil {
 		return
 	}
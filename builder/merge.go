@@ -0,0 +1,117 @@
+package builder
+
+import (
+	output "github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+	"github.com/konveyor/tackle2-hub/shared/api"
+	"go.lsp.dev/uri"
+)
+
+// Merge insights reported by a previous analysis.
+// Incidents reported in files for which changed() returns
+// true are discarded. The remaining incidents are appended to
+// the (matching) rule reported by this analysis.
+// The tags (tag= labels) of the insights with remaining incidents
+// are reported so that the tags are derived from the merged insights.
+func (b *Insights) Merge(previous []api.Insight, changed func(path string) bool) {
+	for _, insight := range previous {
+		var incidents []output.Incident
+		for _, i := range insight.Incidents {
+			if changed(i.File) {
+				continue
+			}
+			line := i.Line
+			incidents = append(
+				incidents,
+				output.Incident{
+					URI:        uri.File(i.File),
+					Message:    i.Message,
+					CodeSnip:   i.CodeSnip,
+					LineNumber: &line,
+					Variables:  i.Facts,
+				})
+		}
+		if len(incidents) == 0 {
+			continue
+		}
+		ruleset := b.ruleSet(insight.RuleSet)
+		violations := b.classified(ruleset, &insight)
+		v, found := violations[insight.Rule]
+		if !found {
+			v = b.violation(&insight)
+		}
+		v.Incidents = append(v.Incidents, incidents...)
+		violations[insight.Rule] = v
+		ruleset.Tags = append(ruleset.Tags, tagLabels(insight.Labels)...)
+	}
+}
+
+// classified returns the collection (violations or insights) for
+// the previous insight. The rule is classified as reported by this
+// analysis. Otherwise, as written by the previous analysis: only
+// violations are written with a category or effort.
+func (b *Insights) classified(ruleset *output.RuleSet, insight *api.Insight) (violations map[string]output.Violation) {
+	if _, found := ruleset.Violations[insight.Rule]; found {
+		violations = ruleset.Violations
+		return
+	}
+	if _, found := ruleset.Insights[insight.Rule]; found {
+		violations = ruleset.Insights
+		return
+	}
+	if insight.Category != "" || insight.Effort > 0 {
+		violations = ruleset.Violations
+	} else {
+		violations = ruleset.Insights
+	}
+	return
+}
+
+// ruleSet returns the named ruleset.
+// Created as needed.
+func (b *Insights) ruleSet(name string) (ruleset *output.RuleSet) {
+	for i := range b.input {
+		ruleset = &b.input[i]
+		if ruleset.Name == name {
+			break
+		}
+		ruleset = nil
+	}
+	if ruleset == nil {
+		b.input = append(
+			b.input,
+			output.RuleSet{Name: name})
+		ruleset = &b.input[len(b.input)-1]
+	}
+	if ruleset.Violations == nil {
+		ruleset.Violations = make(map[string]output.Violation)
+	}
+	if ruleset.Insights == nil {
+		ruleset.Insights = make(map[string]output.Violation)
+	}
+	return
+}
+
+// violation returns a violation for the insight (without incidents).
+func (b *Insights) violation(insight *api.Insight) (v output.Violation) {
+	v = output.Violation{
+		Description: insight.Description,
+		Labels:      insight.Labels,
+	}
+	if insight.Category != "" {
+		category := output.Category(insight.Category)
+		v.Category = &category
+	}
+	if insight.Effort > 0 {
+		effort := insight.Effort
+		v.Effort = &effort
+	}
+	for _, l := range insight.Links {
+		v.Links = append(
+			v.Links,
+			output.Link{
+				URL:   l.URL,
+				Title: l.Title,
+			})
+	}
+	return
+}
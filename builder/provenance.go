@@ -0,0 +1,39 @@
+package builder
+
+// Provenance of the rules used by the analysis.
+type Provenance struct {
+	// Repositories rules repositories.
+	Repositories []RuleRepository `yaml:"repositories,omitempty"`
+	// Files uploaded rules files.
+	Files []RuleFile `yaml:"files,omitempty"`
+	// Bucket rules (task bucket) path.
+	Bucket string `yaml:"bucket,omitempty"`
+}
+
+// Empty returns true when nothing recorded.
+func (p *Provenance) Empty() (b bool) {
+	b = len(p.Repositories) == 0 &&
+		len(p.Files) == 0 &&
+		p.Bucket == ""
+	return
+}
+
+// RuleRepository rules repository provenance.
+type RuleRepository struct {
+	// RuleSet id (when provided by a ruleset).
+	RuleSet uint   `yaml:"ruleSet,omitempty"`
+	URL     string `yaml:"url"`
+	Branch  string `yaml:"branch,omitempty"`
+	Path    string `yaml:"path,omitempty"`
+	Commit  string `yaml:"commit"`
+	// Pinned the commit was pinned.
+	Pinned bool `yaml:"pinned,omitempty"`
+}
+
+// RuleFile rules file provenance.
+type RuleFile struct {
+	// RuleSet id (when provided by a ruleset).
+	RuleSet uint   `yaml:"ruleSet,omitempty"`
+	ID      uint   `yaml:"id"`
+	Name    string `yaml:"name,omitempty"`
+}
@@ -0,0 +1,43 @@
+package builder
+
+import (
+	"os"
+
+	"gopkg.in/yaml.v2"
+)
+
+// AnalysisReport reports what the hub analysis does not record.
+// Attached to the task.
+type AnalysisReport struct {
+	Path string `yaml:"-"`
+	// Unavailable providers.
+	Unavailable []string `yaml:"unavailable,omitempty"`
+	// Provenance of the rules.
+	Provenance *Provenance `yaml:"provenance,omitempty"`
+}
+
+// Empty returns true when nothing to report.
+func (r *AnalysisReport) Empty() (b bool) {
+	b = len(r.Unavailable) == 0 &&
+		r.Provenance == nil
+	return
+}
+
+// Write the report file.
+func (r *AnalysisReport) Write() (err error) {
+	r.Path = "analysis.yaml"
+	file, err := os.Create(r.Path)
+	if err != nil {
+		return
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+	encoder := yaml.NewEncoder(file)
+	err = encoder.Encode(r)
+	if err != nil {
+		return
+	}
+	err = encoder.Close()
+	return
+}
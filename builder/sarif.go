@@ -0,0 +1,289 @@
+package builder
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+
+	output "github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+	"k8s.io/utils/pointer"
+)
+
+// SARIF schema and version.
+const (
+	SarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	SarifVersion = "2.1.0"
+)
+
+// SARIF result levels.
+const (
+	SarifError   = "error"
+	SarifWarning = "warning"
+	SarifNote    = "note"
+)
+
+// Sarif file.
+// SARIF (2.1.0) log containing a single run.
+// Rules are mapped to reportingDescriptor and incidents to result.
+// The sarif visits the insights (as they are walked) and the results
+// are streamed to a temporary file until the log is written.
+type Sarif struct {
+	Path    string
+	rules   []SarifRule
+	results *os.File
+	count   int
+	err     error
+}
+
+// Visit adds the rule and streams the results (incidents).
+func (s *Sarif) Visit(ruleset, ruleid string, v *output.Violation, violation bool) {
+	if s.err != nil {
+		return
+	}
+	if s.results == nil {
+		s.results, s.err = os.CreateTemp("", "sarif-results-*.json")
+		if s.err != nil {
+			return
+		}
+	}
+	category := ""
+	if v.Category != nil {
+		category = string(*v.Category)
+	}
+	effort := pointer.IntDeref(v.Effort, 0)
+	rule := SarifRule{
+		ID:   ruleset + "/" + ruleid,
+		Name: ruleid,
+		ShortDescription: SarifMessage{
+			Text: v.Description,
+		},
+		Properties: map[string]any{
+			"ruleset": ruleset,
+			"labels":  v.Labels,
+		},
+	}
+	if violation {
+		rule.Properties["category"] = category
+		rule.Properties["effort"] = effort
+	}
+	if len(v.Links) > 0 {
+		rule.HelpURI = v.Links[0].URL
+	}
+	index := len(s.rules)
+	s.rules = append(s.rules, rule)
+	level := SarifNote
+	if violation {
+		level = s.level(category)
+	}
+	for _, i := range v.Incidents {
+		region := &SarifRegion{}
+		line := pointer.IntDeref(i.LineNumber, 0)
+		if line > 0 {
+			region.StartLine = line
+		}
+		if i.CodeSnip != "" {
+			region.Snippet = &SarifMessage{Text: i.CodeSnip}
+		}
+		if region.StartLine == 0 && region.Snippet == nil {
+			region = nil
+		}
+		result := SarifResult{
+			RuleID:    rule.ID,
+			RuleIndex: index,
+			Level:     level,
+			Message: SarifMessage{
+				Text: i.Message,
+			},
+			Locations: []SarifLocation{
+				{
+					PhysicalLocation: SarifPhysicalLocation{
+						ArtifactLocation: SarifArtifactLocation{
+							URI: string(i.URI),
+						},
+						Region: region,
+					},
+				},
+			},
+		}
+		if violation {
+			result.Properties = map[string]any{
+				"category": category,
+				"effort":   effort,
+			}
+		}
+		s.write(&result)
+	}
+}
+
+// Write SARIF file.
+// The log is written with the results streamed
+// from the temporary file.
+func (s *Sarif) Write() (err error) {
+	s.Path = "insights.sarif"
+	defer s.close()
+	if s.err != nil {
+		err = s.err
+		return
+	}
+	file, err := os.Create(s.Path)
+	if err != nil {
+		return
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+	tool := SarifTool{
+		Driver: SarifDriver{
+			Name:           "konveyor-analyzer",
+			InformationURI: "https://github.com/konveyor/analyzer-lsp",
+			Rules:          s.rules,
+		},
+	}
+	if tool.Driver.Rules == nil {
+		tool.Driver.Rules = []SarifRule{}
+	}
+	head, err := json.Marshal(
+		SarifLog{
+			Schema:  SarifSchema,
+			Version: SarifVersion,
+			Runs: []SarifRun{
+				{
+					Tool:    tool,
+					Results: []SarifResult{},
+				},
+			},
+		})
+	if err != nil {
+		return
+	}
+	// The (empty) results are last: ...,"results":[]}]}
+	cut := len(head) - len("]}]}")
+	_, err = file.Write(head[:cut])
+	if err != nil {
+		return
+	}
+	if s.results != nil {
+		_, err = s.results.Seek(0, io.SeekStart)
+		if err != nil {
+			return
+		}
+		_, err = io.Copy(file, s.results)
+		if err != nil {
+			return
+		}
+	}
+	_, err = file.Write(head[cut:])
+	return
+}
+
+// write the result to the temporary file.
+func (s *Sarif) write(result *SarifResult) {
+	b, err := json.Marshal(result)
+	if err != nil {
+		s.err = err
+		return
+	}
+	if s.count > 0 {
+		b = append([]byte(","), b...)
+	}
+	_, err = s.results.Write(b)
+	if err != nil {
+		s.err = err
+		return
+	}
+	s.count++
+}
+
+// close and delete the temporary file.
+func (s *Sarif) close() {
+	if s.results == nil {
+		return
+	}
+	_ = s.results.Close()
+	_ = os.Remove(s.results.Name())
+	s.results = nil
+}
+
+// level returns the result level for the category.
+func (s *Sarif) level(category string) (level string) {
+	switch output.Category(category) {
+	case output.Mandatory:
+		level = SarifError
+	case output.Optional:
+		level = SarifWarning
+	default:
+		level = SarifNote
+	}
+	return
+}
+
+// SarifLog SARIF log (root) object.
+type SarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []SarifRun `json:"runs"`
+}
+
+// SarifRun SARIF run object.
+type SarifRun struct {
+	Tool    SarifTool     `json:"tool"`
+	Results []SarifResult `json:"results"`
+}
+
+// SarifTool SARIF tool object.
+type SarifTool struct {
+	Driver SarifDriver `json:"driver"`
+}
+
+// SarifDriver SARIF toolComponent object.
+type SarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri,omitempty"`
+	Rules          []SarifRule `json:"rules"`
+}
+
+// SarifRule SARIF reportingDescriptor object.
+type SarifRule struct {
+	ID               string         `json:"id"`
+	Name             string         `json:"name,omitempty"`
+	ShortDescription SarifMessage   `json:"shortDescription"`
+	HelpURI          string         `json:"helpUri,omitempty"`
+	Properties       map[string]any `json:"properties,omitempty"`
+}
+
+// SarifResult SARIF result object.
+type SarifResult struct {
+	RuleID     string          `json:"ruleId"`
+	RuleIndex  int             `json:"ruleIndex"`
+	Level      string          `json:"level"`
+	Message    SarifMessage    `json:"message"`
+	Locations  []SarifLocation `json:"locations"`
+	Properties map[string]any  `json:"properties,omitempty"`
+}
+
+// SarifMessage SARIF message object.
+type SarifMessage struct {
+	Text string `json:"text"`
+}
+
+// SarifLocation SARIF location object.
+type SarifLocation struct {
+	PhysicalLocation SarifPhysicalLocation `json:"physicalLocation"`
+}
+
+// SarifPhysicalLocation SARIF physicalLocation object.
+type SarifPhysicalLocation struct {
+	ArtifactLocation SarifArtifactLocation `json:"artifactLocation"`
+	Region           *SarifRegion          `json:"region,omitempty"`
+}
+
+// SarifArtifactLocation SARIF artifactLocation object.
+type SarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+// SarifRegion SARIF region object.
+type SarifRegion struct {
+	StartLine int           `json:"startLine,omitempty"`
+	Snippet   *SarifMessage `json:"snippet,omitempty"`
+}
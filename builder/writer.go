@@ -1,6 +1,7 @@
 package builder
 
 import (
+	"bytes"
 	"io"
 
 	"gopkg.in/yaml.v2"
@@ -22,25 +23,56 @@ func (w *Writer) Write(s string) {
 	}
 }
 
-// Encode and write object.
+// Encode and write object (document).
 func (w *Writer) Encode(object any) {
-	_, err := w.wrapped.Write([]byte("---\n"))
+	w.Write("---\n")
+	b, err := yaml.Marshal(object)
 	if err != nil {
 		w.addError(err)
 		return
 	}
-	encoder := yaml.NewEncoder(w.wrapped)
-	err = encoder.Encode(object)
+	w.write(b)
+}
+
+// EncodeItem encode and write object as a (block) sequence item.
+func (w *Writer) EncodeItem(object any) {
+	b, err := yaml.Marshal(object)
 	if err != nil {
 		w.addError(err)
 		return
 	}
-	err = encoder.Close()
+	for n, line := range bytes.SplitAfter(b, []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+		if n == 0 {
+			w.Write("- ")
+		} else {
+			w.Write("  ")
+		}
+		w.write(line)
+	}
+}
+
+// encode and write the fields (block mapping).
+func (w *Writer) encode(fields yaml.MapSlice) {
+	if len(fields) == 0 {
+		return
+	}
+	b, err := yaml.Marshal(fields)
 	if err != nil {
 		w.addError(err)
 		return
 	}
-	return
+	w.write(b)
+}
+
+// write bytes.
+func (w *Writer) write(b []byte) {
+	_, err := w.wrapped.Write(b)
+	if err != nil {
+		w.addError(err)
+	}
 }
 
 // Error returns the first error.
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"sync"
+
+	"github.com/konveyor/tackle2-hub/shared/addon/adapter"
+	"github.com/konveyor/tackle2-hub/shared/api"
+)
+
+// SyncAddon the addon adapter with the task reporting serialized.
+// The adapter is not thread-safe and the analyzer progress is
+// reported on the (progress) reporter goroutine.
+type SyncAddon struct {
+	*adapter.Adapter
+	mutex sync.Mutex
+}
+
+// Activity report addon activity.
+func (a *SyncAddon) Activity(entry string, v ...any) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.Adapter.Activity(entry, v...)
+}
+
+// Error report addon error.
+func (a *SyncAddon) Error(error ...api.TaskError) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.Adapter.Error(error...)
+}
+
+// Errorf report addon error.
+func (a *SyncAddon) Errorf(severity, description string, v ...any) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.Adapter.Errorf(severity, description, v...)
+}
+
+// Failed report addon failed.
+func (a *SyncAddon) Failed(reason string, v ...any) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.Adapter.Failed(reason, v...)
+}
+
+// Attach the file to the last activity.
+func (a *SyncAddon) Attach(f *api.File) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.Adapter.Attach(f)
+}
+
+// AttachAt attach the file to the activity.
+func (a *SyncAddon) AttachAt(f *api.File, activity int) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.Adapter.AttachAt(f, activity)
+}
+
+// Total report addon total items.
+func (a *SyncAddon) Total(n int) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.Adapter.Total(n)
+}
+
+// Increment report addon completed (+1) items.
+func (a *SyncAddon) Increment() {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.Adapter.Increment()
+}
+
+// Completed report addon completed (N) items.
+func (a *SyncAddon) Completed(n int) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.Adapter.Completed(n)
+}
@@ -1,12 +1,16 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path"
 
+	liblogr "github.com/go-logr/logr"
 	"github.com/jortel/go-utils/logr"
 	"github.com/konveyor/analyzer-lsp/core"
+	"github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+	"github.com/konveyor/analyzer-lsp/provider"
 	"github.com/konveyor/tackle2-addon-analyzer/builder"
 	addonprogress "github.com/konveyor/tackle2-addon-analyzer/progress"
 	"github.com/konveyor/tackle2-hub/shared/api"
@@ -16,9 +20,14 @@ import (
 // Analyzer application analyzer.
 type Analyzer struct {
 	*Data
+	settings Settings
+	tracker  ProviderTracker
+	progress *addonprogress.AddonReporter
 }
 
 // Run analyzer.
+// The providers are probed before the analysis. When a provider fails
+// during the analysis, it is retried with a bounded number of attempts.
 func (r *Analyzer) Run() (insights *builder.Insights, deps *builder.Deps, err error) {
 
 	analyzerOpts, err := r.options()
@@ -28,44 +37,42 @@ func (r *Analyzer) Run() (insights *builder.Insights, deps *builder.Deps, err er
 	log := logr.New("analyzer", r.Verbosity+4)
 	analyzerOpts = append(analyzerOpts, core.WithLogger(log))
 
-	analyzerOpts = append(analyzerOpts, core.WithReporters(addonprogress.NewAddonReporter(addon)))
-	// If there are any errors from opts they will be set here.
-	analyzer, err := core.NewAnalyzer(analyzerOpts...)
+	health := &r.Data.Health
+	configs := r.settings.Configs
+	err = health.Probe(configs)
 	if err != nil {
-		addon.Error(api.TaskError{
-			Severity:    "Error",
-			Description: fmt.Sprintf("Unable to start Analyzer errs: %s", err.Error()),
-		})
-		return
+		var pErr *ProviderError
+		if !health.Degrade || !errors.As(err, &pErr) {
+			return
+		}
+		configs, err = health.degraded.Remove(configs, pErr.Providers)
+		if err != nil {
+			return
+		}
 	}
-	defer analyzer.Stop()
 
-	_, err = analyzer.ParseRules()
+	depOutput := path.Join(Dir, "deps.yaml")
+	output := path.Join(Dir, "insights.yaml")
+
+	results, capabilities, err := r.retry(
+		configs,
+		func(configs []provider.Config) ([]konveyor.RuleSet, map[string][]string, error) {
+			return r.run(analyzerOpts, log, depOutput, configs)
+		})
 	if err != nil {
 		return
 	}
 
-	err = analyzer.ProviderStart()
+	err = r.attachProgress()
 	if err != nil {
 		return
 	}
-
-	for _, p := range analyzer.GetProviders() {
-		log.Info("capabilities", "caps", p.Capabilities())
-	}
-
-	depOutput := path.Join(Dir, "deps.yaml")
-	output := path.Join(Dir, "insights.yaml")
-
-	results := analyzer.Run()
-	if !r.Data.Mode.Discovery {
-		depErr := analyzer.GetDependencies(depOutput, false)
-		if depErr != nil {
-			err = depErr
+	if r.Profiling {
+		err = r.attachProfiling(results)
+		if err != nil {
 			return
 		}
 	}
-
 	if r.Verbosity > 0 {
 		// Create the files and post
 		i, mErr := yaml.Marshal(results)
@@ -85,7 +92,7 @@ func (r *Analyzer) Run() (insights *builder.Insights, deps *builder.Deps, err er
 			return
 		}
 
-		f, pErr := addon.File.Post(output)
+		f, pErr := hubApi.FilePost(output)
 		if pErr != nil {
 			err = pErr
 			return
@@ -93,7 +100,7 @@ func (r *Analyzer) Run() (insights *builder.Insights, deps *builder.Deps, err er
 		addon.Attach(f)
 
 		if _, statErr := os.Stat(depOutput); statErr == nil {
-			f, pErr = addon.File.Post(depOutput)
+			f, pErr = hubApi.FilePost(depOutput)
 			if pErr != nil {
 				err = pErr
 				return
@@ -109,34 +116,238 @@ func (r *Analyzer) Run() (insights *builder.Insights, deps *builder.Deps, err er
 	if err != nil {
 		return
 	}
+	insights.WithDeps(deps)
+	insights.WithModules(r.Mode.ModuleNames())
+	skipped, err := health.degraded.Skipped(r.Rules.rules)
+	if err != nil {
+		return
+	}
+	insights.WithSkipped(skipped)
+	for name, names := range capabilities {
+		insights.WithCapabilities(name, names)
+	}
 	return
 }
 
-// options builds Analyzer options.
-func (r *Analyzer) options() (options []core.AnalyzerOption, err error) {
+// retry runs the analysis with a bounded number of attempts.
+// After each failed attempt, the failed providers are probed and the
+// analysis is retried while attempts remain. Otherwise, the failures
+// are reported and (when degraded) the providers are removed and the
+// analysis continued without them. Each degrade removes at least one
+// provider so the number of runs is bounded by attempts + providers.
+// Providers that failed to start are removed without retry.
+func (r *Analyzer) retry(
+	configs []provider.Config,
+	run func(configs []provider.Config) ([]konveyor.RuleSet, map[string][]string, error)) (
+	results []konveyor.RuleSet, capabilities map[string][]string, err error) {
+	health := &r.Data.Health
+	attempt := 1
+	for {
+		results, capabilities, err = run(configs)
+		var names []string
+		if err != nil && health.Degrade {
+			names = r.tracker.Failed(configs, health)
+		}
+		if len(names) == 0 {
+			failed := health.Failed(configs, results, err)
+			if len(failed) == 0 {
+				return
+			}
+			if attempt < health.attempts() {
+				attempt++
+				addon.Activity(
+					"[PROVIDER] %s failed. Retrying (attempt %d of %d).",
+					health.Names(failed),
+					attempt,
+					health.attempts())
+				err = health.Probe(failed)
+				if err == nil {
+					continue
+				}
+			} else {
+				err = health.Report(failed)
+			}
+			if !health.Degrade {
+				return
+			}
+			names = health.names(failed)
+		}
+		remaining, dErr := health.degraded.Remove(configs, names)
+		if dErr != nil || len(remaining) == len(configs) {
+			return
+		}
+		configs = remaining
+	}
+}
+
+// run the analyzer.
+func (r *Analyzer) run(
+	analyzerOpts []core.AnalyzerOption,
+	log liblogr.Logger,
+	depOutput string,
+	configs []provider.Config) (results []konveyor.RuleSet, capabilities map[string][]string, err error) {
+	r.tracker.Reset()
+	r.tracker.Start = func(p provider.Config) (err error) {
+		err = r.start(analyzerOpts, configs, p)
+		return
+	}
+	r.progress = addonprogress.NewAddonReporter(addon)
+	defer r.progress.Close()
+	analyzerOpts = append(
+		analyzerOpts,
+		core.WithProviderConfigs(configs),
+		core.WithReporters(r.progress))
+	// If there are any errors from opts they will be set here.
+	analyzer, err := core.NewAnalyzer(analyzerOpts...)
+	if err != nil {
+		addon.Error(api.TaskError{
+			Severity:    "Error",
+			Description: fmt.Sprintf("Unable to start Analyzer errs: %s", err.Error()),
+		})
+		return
+	}
+	defer analyzer.Stop()
+
+	_, err = analyzer.ParseRules()
+	if err != nil {
+		return
+	}
+	var needed []string
+	for _, p := range analyzer.GetProviders() {
+		needed = append(needed, p.Name)
+	}
+	r.tracker.Needed(needed)
 
-	options = append(options, r.Mode.ToOption())
-	options = append(options, r.Rules.ToOptions()...)
-	options = append(options, r.Scope.ToOptions(r.Mode)...)
-	settings := Settings{}
-	err = settings.AppendExtensions(&r.Mode)
+	err = analyzer.ProviderStart()
 	if err != nil {
+		if r.Health.Degrade {
+			r.tracker.Check(configs)
+		}
 		return
 	}
-	err = settings.ProxySettings()
+
+	capabilities = make(map[string][]string)
+	for _, p := range analyzer.GetProviders() {
+		log.Info("capabilities", "caps", p.Capabilities())
+		for _, c := range p.Capabilities() {
+			capabilities[p.Name] = append(capabilities[p.Name], c.Name)
+		}
+	}
+
+	results = analyzer.Run()
+	if !r.Data.Mode.Discovery {
+		err = analyzer.GetDependencies(depOutput, false)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+// start the provider in isolation (with builtin).
+// Returns the error reported by the provider.
+func (r *Analyzer) start(
+	analyzerOpts []core.AnalyzerOption,
+	configs []provider.Config,
+	p provider.Config) (err error) {
+	isolated := []provider.Config{p}
+	for _, c := range configs {
+		if c.Name == Builtin {
+			isolated = append(isolated, c)
+		}
+	}
+	analyzerOpts = append(
+		analyzerOpts,
+		core.WithProviderConfigs(isolated))
+	analyzer, err := core.NewAnalyzer(analyzerOpts...)
+	if err != nil {
+		return
+	}
+	defer analyzer.Stop()
+	_, err = analyzer.ParseRules()
 	if err != nil {
 		return
 	}
+	err = analyzer.ProviderStart()
+	return
+}
+
+// options builds Analyzer options.
+func (r *Analyzer) options() (options []core.AnalyzerOption, err error) {
+
+	discovery := Discovery{Data: r.Data}
+	if r.Mode.Discovery {
+		options = append(options, discovery.ToOptions()...)
+	} else {
+		options = append(options, r.Rules.ToOptions()...)
+		options = append(options, r.Scope.ToOptions(r.Mode)...)
+	}
+	settings := &r.settings
+	err = settings.Build(&r.Mode)
+	if err != nil {
+		return
+	}
+	if r.Mode.Discovery {
+		settings.Configs, err = discovery.Providers(settings.Configs)
+		if err != nil {
+			return
+		}
+	}
 	err = settings.Write()
 	if err != nil {
 		return
 	}
-	f, pErr := addon.File.Post(settings.path())
+	f, pErr := hubApi.FilePost(settings.path())
 	if pErr != nil {
 		err = pErr
 		return
 	}
 	addon.Attach(f)
-	options = append(options, core.WithProviderConfigs(settings.Configs))
+	return
+}
+
+// attachProgress attaches the progress summary.
+// Reports the stages and the slowest providers and rules.
+func (r *Analyzer) attachProgress() (err error) {
+	summary := r.progress.Summary()
+	b, err := yaml.Marshal(summary)
+	if err != nil {
+		return
+	}
+	p := path.Join(Dir, "progress.yaml")
+	err = os.WriteFile(p, b, 0644)
+	if err != nil {
+		return
+	}
+	f, err := hubApi.FilePost(p)
+	if err != nil {
+		return
+	}
+	addon.Attach(f)
+	return
+}
+
+// attachProfiling attaches the rule profiling report.
+// The wall time of each rule is reported by the progress reporter.
+func (r *Analyzer) attachProfiling(results []konveyor.RuleSet) (err error) {
+	files := RuleFiles{Paths: r.Rules.rules}
+	rules, err := files.Rules()
+	if err != nil {
+		return
+	}
+	profiling := Profiling{}
+	profiling.Build(results, rules, r.progress.Rules())
+	paths, err := profiling.Write(Dir)
+	if err != nil {
+		return
+	}
+	for _, p := range paths {
+		f, pErr := hubApi.FilePost(p)
+		if pErr != nil {
+			err = pErr
+			return
+		}
+		addon.Attach(f)
+	}
 	return
 }
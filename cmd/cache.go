@@ -0,0 +1,395 @@
+package main
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/konveyor/tackle2-hub/shared/api"
+	"github.com/konveyor/tackle2-hub/shared/nas"
+)
+
+var (
+	// RuleCacheRefresh cached repositories fetched (or updated) within
+	// the duration are not updated.
+	RuleCacheRefresh = 10 * time.Minute
+	// RuleCacheMaxAge cached entries not used within the duration are evicted.
+	RuleCacheMaxAge = 7 * 24 * time.Hour
+)
+
+// RuleCache content-addressed cache of rules shared by tasks.
+// Entries are created in a temporary directory and renamed when complete.
+// Each entry is locked (flock) so concurrent tasks sharing the cache
+// volume do not corrupt each other.
+type RuleCache struct {
+}
+
+// Get copies the cached entry to the destination directory.
+// The entry is populated using fetch() when not cached.
+func (r *RuleCache) Get(key, destination string, fetch func(dir string) (err error)) (err error) {
+	entry := path.Join(RuleCacheDir, key)
+	unlock, err := r.lock(entry)
+	if err != nil {
+		return
+	}
+	defer unlock()
+	err = r.touch(entry + ".lock")
+	if err != nil {
+		return
+	}
+	found, err := nas.HasDir(entry)
+	if err != nil {
+		return
+	}
+	if found {
+		addon.Activity("[RULESET] cached: %s", key)
+	} else {
+		tmp := entry + ".tmp"
+		err = os.RemoveAll(tmp)
+		if err != nil {
+			return
+		}
+		err = nas.MkDir(tmp, 0755)
+		if err != nil {
+			return
+		}
+		err = fetch(tmp)
+		if err != nil {
+			_ = os.RemoveAll(tmp)
+			return
+		}
+		err = os.Rename(tmp, entry)
+		if err != nil {
+			return
+		}
+	}
+	err = r.copy(entry, destination)
+	return
+}
+
+// Repository copies the cached repository to the destination directory.
+// The cached clone is updated using the remote; cloned when not cached
+// or the update failed. The update is skipped when the repository is
+// pinned or was fetched within RuleCacheRefresh unless forced.
+// Returns the commit.
+func (r *RuleCache) Repository(
+	repository api.Repository,
+	identity *api.Identity,
+	pinned bool,
+	force bool,
+	destination string) (commit string, err error) {
+	parts := []string{
+		repository.Kind,
+		repository.URL,
+		repository.Branch,
+		repository.Tag,
+	}
+	if identity != nil {
+		parts = append(parts, fmt.Sprintf("%d", identity.ID))
+	}
+	key := "repository/" + r.digest(parts...)
+	entry := path.Join(RuleCacheDir, key)
+	unlock, err := r.lock(entry)
+	if err != nil {
+		return
+	}
+	defer unlock()
+	err = r.touch(entry + ".lock")
+	if err != nil {
+		return
+	}
+	rp, err := hubApi.Repository(entry, repository, identity)
+	if err != nil {
+		return
+	}
+	found, err := nas.HasDir(entry)
+	if err != nil {
+		return
+	}
+	if found && (force || (!pinned && !r.fresh(entry))) {
+		err = rp.Update()
+		if err != nil {
+			addon.Activity(
+				"[RULESET] cached: %s update failed: %s",
+				key,
+				err.Error())
+			found = false
+		} else {
+			err = r.touch(entry + ".fetched")
+			if err != nil {
+				return
+			}
+		}
+	}
+	if !found {
+		err = os.RemoveAll(entry)
+		if err != nil {
+			return
+		}
+		err = rp.Fetch()
+		if err != nil {
+			_ = os.RemoveAll(entry)
+			return
+		}
+		err = r.touch(entry + ".fetched")
+		if err != nil {
+			return
+		}
+	}
+	commit, err = rp.Head()
+	if err != nil {
+		return
+	}
+	addon.Activity(
+		"[RULESET] cached: %s url: %s commit: %s",
+		key,
+		repository.URL,
+		commit)
+	err = r.copy(entry, destination)
+	return
+}
+
+// Evict removes the entries not used within RuleCacheMaxAge.
+// Each entry is locked while removed.
+func (r *RuleCache) Evict() (err error) {
+	var stale []string
+	err = filepath.WalkDir(
+		RuleCacheDir,
+		func(p string, d fs.DirEntry, wErr error) (err error) {
+			if wErr != nil {
+				err = wErr
+				return
+			}
+			if d.IsDir() || !strings.HasSuffix(p, ".lock") {
+				return
+			}
+			if r.used(p) {
+				return
+			}
+			stale = append(stale, strings.TrimSuffix(p, ".lock"))
+			return
+		})
+	if err != nil {
+		return
+	}
+	for _, entry := range stale {
+		err = r.evict(entry)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+// evict removes the entry when (still) not used within RuleCacheMaxAge.
+func (r *RuleCache) evict(entry string) (err error) {
+	unlock, err := r.lock(entry)
+	if err != nil {
+		return
+	}
+	defer unlock()
+	if r.used(entry + ".lock") {
+		return
+	}
+	for _, p := range []string{entry, entry + ".tmp", entry + ".fetched"} {
+		err = os.RemoveAll(p)
+		if err != nil {
+			return
+		}
+	}
+	err = os.Remove(entry + ".lock")
+	if err != nil {
+		return
+	}
+	rel, _ := filepath.Rel(RuleCacheDir, entry)
+	addon.Activity("[RULESET] cached: %s evicted.", rel)
+	return
+}
+
+// used returns true when the entry lock was modified within
+// RuleCacheMaxAge. The lock is touched each time the entry is used.
+func (r *RuleCache) used(lock string) (used bool) {
+	st, err := os.Stat(lock)
+	if err != nil {
+		return
+	}
+	used = time.Since(st.ModTime()) < RuleCacheMaxAge
+	return
+}
+
+// fresh returns true when the entry was fetched within RuleCacheRefresh.
+func (r *RuleCache) fresh(entry string) (fresh bool) {
+	st, err := os.Stat(entry + ".fetched")
+	if err != nil {
+		return
+	}
+	fresh = time.Since(st.ModTime()) < RuleCacheRefresh
+	return
+}
+
+// touch creates the file or updates the modification time.
+func (r *RuleCache) touch(p string) (err error) {
+	f, err := os.OpenFile(p, os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return
+	}
+	_ = f.Close()
+	now := time.Now()
+	err = os.Chtimes(p, now, now)
+	return
+}
+
+// RuleSetKey returns the key for the ruleset.
+// The hub does not report when a ruleset is updated so the key
+// includes the ids of the (immutable) rule files.
+func (r *RuleCache) RuleSetKey(ruleset *api.RuleSet) (key string) {
+	var parts []string
+	for _, rule := range ruleset.Rules {
+		if rule.File != nil {
+			parts = append(
+				parts,
+				fmt.Sprintf("%d:%s", rule.File.ID, rule.File.Name))
+		}
+	}
+	key = fmt.Sprintf("ruleset/%d-%s", ruleset.ID, r.digest(parts...))
+	return
+}
+
+// FilesKey returns the key for the (immutable) files.
+func (r *RuleCache) FilesKey(files []api.Ref) (key string) {
+	var parts []string
+	for _, ref := range files {
+		parts = append(parts, fmt.Sprintf("%d", ref.ID))
+	}
+	key = "files/" + r.digest(parts...)
+	return
+}
+
+// lock the entry.
+// The lock is retried when the lock file was removed (evicted) while
+// waiting. Returns the function to unlock.
+func (r *RuleCache) lock(entry string) (unlock func(), err error) {
+	err = nas.MkDir(path.Dir(entry), 0755)
+	if err != nil {
+		return
+	}
+	p := entry + ".lock"
+	for {
+		var f *os.File
+		f, err = os.OpenFile(p, os.O_CREATE|os.O_RDWR, 0644)
+		if err != nil {
+			return
+		}
+		fd := int(f.Fd())
+		err = syscall.Flock(fd, syscall.LOCK_EX)
+		if err != nil {
+			_ = f.Close()
+			return
+		}
+		if !r.removed(f, p) {
+			unlock = func() {
+				_ = syscall.Flock(fd, syscall.LOCK_UN)
+				_ = f.Close()
+			}
+			break
+		}
+		_ = syscall.Flock(fd, syscall.LOCK_UN)
+		_ = f.Close()
+	}
+	return
+}
+
+// removed returns true when the (open) lock file is no longer
+// the file at the path.
+func (r *RuleCache) removed(f *os.File, p string) (removed bool) {
+	opened, err := f.Stat()
+	if err != nil {
+		removed = true
+		return
+	}
+	current, err := os.Stat(p)
+	if err != nil {
+		removed = true
+		return
+	}
+	removed = !os.SameFile(opened, current)
+	return
+}
+
+// copy the entry content to the destination directory.
+// Symbolic links are copied as links.
+func (r *RuleCache) copy(entry, destination string) (err error) {
+	err = filepath.WalkDir(
+		entry,
+		func(p string, d fs.DirEntry, wErr error) (err error) {
+			if wErr != nil {
+				err = wErr
+				return
+			}
+			rel, err := filepath.Rel(entry, p)
+			if err != nil {
+				return
+			}
+			target := filepath.Join(destination, rel)
+			switch {
+			case d.IsDir():
+				err = os.MkdirAll(target, 0755)
+			case d.Type()&fs.ModeSymlink != 0:
+				var link string
+				link, err = os.Readlink(p)
+				if err != nil {
+					return
+				}
+				err = os.Symlink(link, target)
+			default:
+				err = r.copyFile(p, target)
+			}
+			return
+		})
+	return
+}
+
+// copyFile copies the file.
+func (r *RuleCache) copyFile(path, destination string) (err error) {
+	st, err := os.Stat(path)
+	if err != nil {
+		return
+	}
+	reader, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer func() {
+		_ = reader.Close()
+	}()
+	writer, err := os.OpenFile(
+		destination,
+		os.O_CREATE|os.O_TRUNC|os.O_WRONLY,
+		st.Mode().Perm())
+	if err != nil {
+		return
+	}
+	defer func() {
+		_ = writer.Close()
+	}()
+	_, err = io.Copy(writer, reader)
+	return
+}
+
+// digest returns the digest of the parts.
+func (r *RuleCache) digest(parts ...string) (d string) {
+	h := sha256.New()
+	for _, part := range parts {
+		_, _ = h.Write([]byte(part))
+		_, _ = h.Write([]byte{0})
+	}
+	d = fmt.Sprintf("%x", h.Sum(nil))[:16]
+	return
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/konveyor/tackle2-hub/shared/api"
+	"github.com/onsi/gomega"
+)
+
+func TestRuleCache(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	tmp := useDirs(t)
+	repoDir := path.Join(tmp, "remote")
+	err := os.MkdirAll(path.Join(repoDir, "rules"), 0755)
+	g.Expect(err).To(gomega.BeNil())
+	err = os.WriteFile(path.Join(repoDir, "rules", "rules.yaml"), []byte("[]\n"), 0644)
+	g.Expect(err).To(gomega.BeNil())
+	hub := &FakeHub{
+		Repositories: map[string]string{"http://rules": repoDir},
+	}
+	useFakeHub(t, hub)
+	cache := RuleCache{}
+	// files.
+	var fetched atomic.Int32
+	fetch := func(dir string) (err error) {
+		fetched.Add(1)
+		time.Sleep(10 * time.Millisecond)
+		err = os.WriteFile(path.Join(dir, "rule.yaml"), []byte("[]\n"), 0644)
+		return
+	}
+	ruleset := &api.RuleSet{
+		Resource: api.Resource{ID: 1},
+		Rules: []api.Rule{
+			{File: &api.Ref{ID: 10, Name: "rule.yaml"}},
+		},
+	}
+	key := cache.RuleSetKey(ruleset)
+	wg := sync.WaitGroup{}
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			dest := path.Join(tmp, "dest", strconv.Itoa(i))
+			gErr := cache.Get(key, dest, fetch)
+			g.Expect(gErr).To(gomega.BeNil())
+			g.Expect(path.Join(dest, "rule.yaml")).To(gomega.BeARegularFile())
+		}(i)
+	}
+	wg.Wait()
+	g.Expect(fetched.Load()).To(gomega.Equal(int32(1)))
+	// key changed when the rule files changed.
+	ruleset.Rules[0].File.ID = 11
+	g.Expect(cache.RuleSetKey(ruleset)).NotTo(gomega.Equal(key))
+	g.Expect(cache.FilesKey([]api.Ref{{ID: 1}})).NotTo(gomega.Equal(cache.FilesKey([]api.Ref{{ID: 2}})))
+	// failed fetch not cached.
+	err = cache.Get("failed", path.Join(tmp, "failed"), func(string) error { return errors.New("failed") })
+	g.Expect(err).NotTo(gomega.BeNil())
+	g.Expect(path.Join(RuleCacheDir, "failed")).NotTo(gomega.BeADirectory())
+	// repository.
+	repository := api.Repository{URL: "http://rules", Path: "rules"}
+	for i := 0; i < 2; i++ {
+		dest := path.Join(tmp, "repository", strconv.Itoa(i))
+		commit, rErr := cache.Repository(repository, nil, false, false, dest)
+		g.Expect(rErr).To(gomega.BeNil())
+		g.Expect(commit).To(gomega.Equal("abc123"))
+		g.Expect(path.Join(dest, "rules", "rules.yaml")).To(gomega.BeARegularFile())
+	}
+	// recently fetched not updated.
+	g.Expect(hub.Updated).To(gomega.Equal(0))
+	saved := RuleCacheRefresh
+	RuleCacheRefresh = 0
+	defer func() {
+		RuleCacheRefresh = saved
+	}()
+	_, err = cache.Repository(repository, nil, false, false, path.Join(tmp, "repository", "2"))
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(hub.Updated).To(gomega.Equal(1))
+	// pinned not updated.
+	_, err = cache.Repository(repository, nil, true, false, path.Join(tmp, "repository", "3"))
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(hub.Updated).To(gomega.Equal(1))
+	// forced.
+	RuleCacheRefresh = saved
+	_, err = cache.Repository(repository, nil, true, true, path.Join(tmp, "repository", "5"))
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(hub.Updated).To(gomega.Equal(2))
+	// keyed by identity.
+	identity := &api.Identity{Resource: api.Resource{ID: 1}}
+	_, err = cache.Repository(repository, identity, false, false, path.Join(tmp, "repository", "4"))
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(hub.Updated).To(gomega.Equal(2))
+	entries, err := os.ReadDir(path.Join(RuleCacheDir, "repository"))
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(entries)).To(gomega.Equal(6))
+	// evicted when not used.
+	old := time.Now().Add(-2 * RuleCacheMaxAge)
+	lock := path.Join(RuleCacheDir, key+".lock")
+	err = os.Chtimes(lock, old, old)
+	g.Expect(err).To(gomega.BeNil())
+	err = cache.Evict()
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(path.Join(RuleCacheDir, key)).NotTo(gomega.BeADirectory())
+	g.Expect(lock).NotTo(gomega.BeAnExistingFile())
+	entries, err = os.ReadDir(path.Join(RuleCacheDir, "repository"))
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(entries)).To(gomega.Equal(6))
+}
@@ -3,13 +3,17 @@ package main
 import (
 	"errors"
 	"os"
+	"os/exec"
 	path2 "path"
 	"path/filepath"
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
+	output "github.com/konveyor/analyzer-lsp/output/v1/konveyor"
 	"github.com/konveyor/analyzer-lsp/provider"
+	"github.com/konveyor/tackle2-addon-analyzer/builder"
 	"github.com/konveyor/tackle2-hub/shared/api"
 	"github.com/konveyor/tackle2-hub/shared/binding"
 	"github.com/konveyor/tackle2-hub/shared/binding/client"
@@ -415,8 +419,73 @@ func TestRawInject(t *testing.T) {
 	g.Expect(md2["Location"]).To(gomega.Equal(path))
 }
 
+func TestExpression(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	inj := Injector{}
+	inj.Use(map[string]any{
+		"builtin.location": "/shared/source/app",
+		"builtin.mode":     "full",
+		"user":             "Elmer",
+		"empty":            "",
+		"list":             []any{"a", "b"},
+	})
+	md := map[string]any{
+		"literal":  "$(user)",
+		"missing":  "$(missing)",
+		"default":  "$(missing:-/opt/settings.xml)",
+		"empty":    "$(empty:-none)",
+		"set":      "$(user:-none)",
+		"lower":    "$(user | lower)",
+		"base64":   "$(user | lower | base64)",
+		"join":     "$(list | join ';')",
+		"joinDef":  "$(list | join)",
+		"mode":     "$(builtin.mode == 'full' ? 'yes' : 'no')",
+		"notMode":  "$(builtin.mode != 'full' ? 'yes' : 'no')",
+		"truthy":   "$(empty ? user : 'none')",
+		"negated":  "$(!empty ? user : 'none')",
+		"embedded": "-Dlocation=$(builtin.location) -Duser=$(user | upper)",
+		"raw":      "$(list)",
+	}
+	md = inj.inject(md).(map[string]any)
+	g.Expect(inj.err).To(gomega.BeNil())
+	g.Expect(md["literal"]).To(gomega.Equal("Elmer"))
+	g.Expect(md["missing"]).To(gomega.BeNil())
+	g.Expect(md["default"]).To(gomega.Equal("/opt/settings.xml"))
+	g.Expect(md["empty"]).To(gomega.Equal("none"))
+	g.Expect(md["set"]).To(gomega.Equal("Elmer"))
+	g.Expect(md["lower"]).To(gomega.Equal("elmer"))
+	g.Expect(md["base64"]).To(gomega.Equal("ZWxtZXI="))
+	g.Expect(md["join"]).To(gomega.Equal("a;b"))
+	g.Expect(md["joinDef"]).To(gomega.Equal("a,b"))
+	g.Expect(md["mode"]).To(gomega.Equal("yes"))
+	g.Expect(md["notMode"]).To(gomega.Equal("no"))
+	g.Expect(md["truthy"]).To(gomega.Equal("none"))
+	g.Expect(md["negated"]).To(gomega.Equal("Elmer"))
+	g.Expect(md["embedded"]).To(gomega.Equal("-Dlocation=/shared/source/app -Duser=ELMER"))
+	g.Expect(md["raw"]).To(gomega.Equal([]any{"a", "b"}))
+	// required.
+	inj.err = nil
+	_ = inj.inject(map[string]any{"required": "$(token:?token must be provided)"})
+	g.Expect(errors.Is(inj.err, &ExpressionError{})).To(gomega.BeTrue())
+	g.Expect(inj.err.Error()).To(gomega.ContainSubstring("token must be provided"))
+	// function not supported.
+	inj.err = nil
+	_ = inj.inject(map[string]any{"fn": "$(user | reverse)"})
+	g.Expect(errors.Is(inj.err, &ExpressionError{})).To(gomega.BeTrue())
+	// reported by Inject.
+	inj.err = nil
+	m := &Metadata{}
+	m.Provider.InitConfig = []provider.InitConfig{
+		{Location: "$(location:?)"},
+	}
+	err := inj.Inject(m)
+	g.Expect(errors.Is(err, &ExpressionError{})).To(gomega.BeTrue())
+}
+
 func TestProfile(t *testing.T) {
 	g := gomega.NewGomegaWithT(t)
+	useDirs(t)
 
 	var fetched struct {
 		targets []uint
@@ -1512,3 +1581,296 @@ func TestResourceInjectorTypeCastError(t *testing.T) {
 	g.Expect(errors.Is(err, &TypeError{})).To(gomega.BeTrue())
 	g.Expect(err.Error()).To(gomega.ContainSubstring("cast failed"))
 }
+
+func TestGate(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	mandatory := output.Mandatory
+	optional := output.Optional
+	effort := 2
+	insights, _ := builder.NewInsights(
+		[]output.RuleSet{
+			{
+				Name: "RULESET-A",
+				Violations: map[string]output.Violation{
+					"rule-001": {
+						Category: &mandatory,
+						Effort:   &effort,
+						Incidents: []output.Incident{
+							{URI: "file:///path"},
+							{URI: "file:///path2"},
+						},
+					},
+					"rule-002": {
+						Category: &optional,
+						Effort:   &effort,
+						Incidents: []output.Incident{
+							{URI: "file:///path"},
+						},
+					},
+				},
+			},
+		})
+	diff := builder.NewDiff(nil)
+	insights.WithVisitors(diff)
+	insights.Visit()
+	diff.Build()
+	// not specified.
+	gate := Gate{}
+	err := gate.Evaluate(insights, diff)
+	g.Expect(err).To(gomega.BeNil())
+	// within thresholds.
+	maxEffort := 6
+	maxMandatory := 2
+	maxNew := 3
+	gate = Gate{
+		MaxEffort:    &maxEffort,
+		MaxMandatory: &maxMandatory,
+		MaxNew:       &maxNew,
+		Categories:   []string{"potential"},
+	}
+	err = gate.Evaluate(insights, diff)
+	g.Expect(err).To(gomega.BeNil())
+	// exceeded.
+	maxEffort = 5
+	maxMandatory = 0
+	maxNew = 0
+	gate.Categories = []string{"optional"}
+	err = gate.Evaluate(insights, diff)
+	g.Expect(errors.Is(err, &GateError{})).To(gomega.BeTrue())
+	var gateErr *GateError
+	errors.As(err, &gateErr)
+	g.Expect(len(gateErr.Violations)).To(gomega.Equal(4))
+}
+
+func TestLocal(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	tmp := useDirs(t)
+	write := func(p, content string) {
+		p = path2.Join(tmp, p)
+		err := os.MkdirAll(path2.Dir(p), 0755)
+		g.Expect(err).To(gomega.BeNil())
+		err = os.WriteFile(p, []byte(content), 0644)
+		g.Expect(err).To(gomega.BeNil())
+	}
+	write("app.yaml", "name: app1\n")
+	write("data.yaml", "tagger:\n  enabled: true\n")
+	write("rules/rs1/ruleset.yaml", "name: rs1\nlabels: [konveyor.io/target=t1]\n")
+	write("rules/rs1/rules.yaml", "- ruleID: r1\n")
+	write("source/src/a.txt", "hello\n")
+	local := &Local{}
+	err := local.Parse([]string{
+		"-application", path2.Join(tmp, "app.yaml"),
+		"-data", path2.Join(tmp, "data.yaml"),
+		"-rules", path2.Join(tmp, "rules"),
+		"-source", path2.Join(tmp, "source"),
+		"-output", path2.Join(tmp, "output"),
+	})
+	g.Expect(err).To(gomega.BeNil())
+	err = local.Load()
+	g.Expect(err).To(gomega.BeNil())
+	// task
+	richClient := &binding.RichClient{}
+	richClient.Use(local.client())
+	addon.Use(richClient)
+	addon.Load()
+	task, err := richClient.Task.Get(1)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(task.Application.ID).To(gomega.Equal(uint(1)))
+	d := &Data{}
+	err = local.DataWith(d)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(d.Tagger.Enabled).To(gomega.BeTrue())
+	// application
+	application, err := local.Application()
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(application.Name).To(gomega.Equal("app1"))
+	g.Expect(application.Repository.Kind).To(gomega.Equal(LocalKind))
+	// source
+	repository, err := local.Repository(path2.Join(tmp, "fetched"), *application.Repository, nil)
+	g.Expect(err).To(gomega.BeNil())
+	err = repository.Fetch()
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(path2.Join(tmp, "fetched", "src", "a.txt")).To(gomega.BeARegularFile())
+	// git repository (without the hub).
+	remote := path2.Join(tmp, "remote")
+	write("remote/b.txt", "hello\n")
+	for _, args := range [][]string{
+		{"init", "-q", "-b", "main"},
+		{"add", "."},
+		{"-c", "user.name=test", "-c", "user.email=test@test", "commit", "-q", "-m", "init"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = remote
+		out, xErr := cmd.CombinedOutput()
+		g.Expect(xErr).To(gomega.BeNil(), string(out))
+	}
+	repository, err = local.Repository(
+		path2.Join(tmp, "cloned"),
+		api.Repository{Kind: "git", URL: "file://" + remote},
+		nil)
+	g.Expect(err).To(gomega.BeNil())
+	err = repository.Fetch()
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(path2.Join(tmp, "cloned", "b.txt")).To(gomega.BeARegularFile())
+	_, err = local.Repository(
+		path2.Join(tmp, "insecure"),
+		api.Repository{Kind: "git", URL: "http://git.example.com/app.git"},
+		nil)
+	g.Expect(err).NotTo(gomega.BeNil())
+	// rulesets
+	ruleSets, err := local.RuleSets()
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(ruleSets)).To(gomega.Equal(1))
+	g.Expect(ruleSets[0].Name).To(gomega.Equal("rs1"))
+	g.Expect(len(ruleSets[0].Rules)).To(gomega.Equal(1))
+	g.Expect(ruleSets[0].Rules[0].Labels).To(gomega.Equal([]string{"konveyor.io/target=t1"}))
+	dest := path2.Join(tmp, "fetched.yaml")
+	err = local.FileGet(ruleSets[0].Rules[0].File.ID, dest)
+	g.Expect(err).To(gomega.BeNil())
+	content, _ := os.ReadFile(dest)
+	g.Expect(string(content)).To(gomega.Equal("- ruleID: r1\n"))
+	// settings
+	insecure := true
+	err = local.Setting("git.insecure.enabled", &insecure)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(insecure).To(gomega.BeFalse())
+	err = local.Setting("unknown", &insecure)
+	g.Expect(errors.Is(err, &api.NotFound{})).To(gomega.BeTrue())
+	// proxies
+	proxy, err := local.Proxy("http")
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(proxy).To(gomega.BeNil())
+	// tags
+	category := api.TagCategory{Name: "Language"}
+	err = local.TagCategoryEnsure(&category)
+	g.Expect(err).To(gomega.BeNil())
+	tag := api.Tag{Name: "Java", Category: api.Ref{ID: category.ID}}
+	err = local.TagEnsure(&tag)
+	g.Expect(err).To(gomega.BeNil())
+	again := api.Tag{Name: "Java", Category: api.Ref{ID: category.ID}}
+	err = local.TagEnsure(&again)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(again.ID).To(gomega.Equal(tag.ID))
+	err = local.AppTagReplace(1, "Analysis", []uint{tag.ID})
+	g.Expect(err).To(gomega.BeNil())
+	content, _ = os.ReadFile(path2.Join(tmp, "output", "tags.yaml"))
+	g.Expect(string(content)).To(gomega.Equal("Analysis:\n  - Language=Java\n"))
+	refs, err := local.AppTagList(1)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(refs).To(gomega.Equal([]api.TagRef{{ID: tag.ID, Source: "Analysis"}}))
+	// tags and evidence (tagger).
+	saved := hubApi
+	hubApi = local
+	defer func() {
+		hubApi = saved
+	}()
+	tagger := Tagger{Enabled: true, Source: "Analysis"}
+	err = tagger.Update(1, []string{"Language=Go"})
+	g.Expect(err).To(gomega.BeNil())
+	err = tagger.Attach(map[string][]builder.TagEvidence{
+		"Language=Go": {{RuleSet: "rs1", Rule: "r1", File: "main.go", Line: 1}},
+	})
+	g.Expect(err).To(gomega.BeNil())
+	content, _ = os.ReadFile(path2.Join(tmp, "output", "tags.yaml"))
+	g.Expect(string(content)).To(gomega.Equal("Analysis:\n  - Language=Go\n"))
+	content, _ = os.ReadFile(path2.Join(tmp, "output", "tag-evidence.yaml"))
+	g.Expect(string(content)).To(gomega.ContainSubstring("tag: Language=Go"))
+	g.Expect(string(content)).To(gomega.ContainSubstring("file: main.go"))
+	// analysis
+	manifest := path2.Join(tmp, "manifest.yaml")
+	write("manifest.yaml", "manifest")
+	reported, err := local.AnalysisUpload(1, manifest)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(reported.ID).ToNot(gomega.BeZero())
+	content, _ = os.ReadFile(path2.Join(tmp, "output", "manifest.yaml"))
+	g.Expect(string(content)).To(gomega.Equal("manifest"))
+	_, err = local.Analysis(1)
+	g.Expect(errors.Is(err, &api.NotFound{})).To(gomega.BeTrue())
+}
+
+func TestProfiling(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	dir := t.TempDir()
+	err := os.WriteFile(path2.Join(dir, "ruleset.yaml"), []byte("name: test\n"), 0644)
+	g.Expect(err).To(gomega.BeNil())
+	rules := `
+- ruleID: rule-001
+  when:
+    or:
+    - java.referenced:
+        pattern: org.test.*
+    - java.dependency:
+        name: junit
+    - builtin.file:
+        pattern: x.xml
+- ruleID: rule-002
+  when:
+    builtin.file:
+      pattern: x.xml
+`
+	err = os.WriteFile(path2.Join(dir, "rules.yaml"), []byte(rules), 0644)
+	g.Expect(err).To(gomega.BeNil())
+	other := t.TempDir()
+	err = os.WriteFile(path2.Join(other, "ruleset.yaml"), []byte("name: other\n"), 0644)
+	g.Expect(err).To(gomega.BeNil())
+	rules = `
+- ruleID: rule-001
+  when:
+    builtin.file:
+      pattern: x.xml
+`
+	err = os.WriteFile(path2.Join(other, "rules.yaml"), []byte(rules), 0644)
+	g.Expect(err).To(gomega.BeNil())
+	files := RuleFiles{Paths: []string{dir, other}}
+	parsed, err := files.Rules()
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(parsed)).To(gomega.Equal(3))
+	results := []output.RuleSet{
+		{
+			Name:      "other",
+			Unmatched: []string{"rule-001"},
+		},
+		{
+			Name: "test",
+			Violations: map[string]output.Violation{
+				"rule-001": {Incidents: []output.Incident{{}, {}}},
+			},
+			Unmatched: []string{"rule-002"},
+			Errors:    map[string]string{"rule-003": "failed"},
+		},
+	}
+	timing := map[string]time.Duration{
+		"rule-001": time.Second,
+		"rule-002": 3 * time.Second,
+	}
+	profiling := Profiling{}
+	profiling.Build(results, parsed, timing)
+	g.Expect(len(profiling.Rules)).To(gomega.Equal(4))
+	// sorted by wall time.
+	g.Expect(profiling.Rules[0].Rule).To(gomega.Equal("rule-002"))
+	g.Expect(profiling.Rules[0].Status).To(gomega.Equal(RuleStatusUnmatched))
+	g.Expect(profiling.Rules[0].Duration).To(gomega.Equal("3s"))
+	g.Expect(profiling.Rules[0].Calls).To(gomega.Equal(1))
+	// same rule (id) in another ruleset.
+	g.Expect(profiling.Rules[1].RuleSet).To(gomega.Equal("other"))
+	g.Expect(profiling.Rules[1].Rule).To(gomega.Equal("rule-001"))
+	g.Expect(profiling.Rules[1].Duration).To(gomega.Equal("1s"))
+	g.Expect(profiling.Rules[1].Calls).To(gomega.Equal(1))
+	g.Expect(profiling.Rules[1].Providers).To(gomega.Equal([]string{"builtin"}))
+	g.Expect(profiling.Rules[2].RuleSet).To(gomega.Equal("test"))
+	g.Expect(profiling.Rules[2].Rule).To(gomega.Equal("rule-001"))
+	g.Expect(profiling.Rules[2].Incidents).To(gomega.Equal(2))
+	g.Expect(profiling.Rules[2].Calls).To(gomega.Equal(3))
+	g.Expect(profiling.Rules[2].Providers).To(gomega.Equal([]string{"builtin", "java"}))
+	g.Expect(profiling.Rules[3].Status).To(gomega.Equal(RuleStatusError))
+	g.Expect(profiling.Rules[3].Duration).To(gomega.Equal("0s"))
+	paths, err := profiling.Write(dir)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(paths)).To(gomega.Equal(2))
+	b, err := os.ReadFile(paths[1])
+	g.Expect(err).To(gomega.BeNil())
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	g.Expect(len(lines)).To(gomega.Equal(5))
+	g.Expect(lines[0]).To(gomega.Equal("ruleset,rule,status,duration_ms,incidents,calls,providers"))
+	g.Expect(lines[3]).To(gomega.Equal("test,rule-001,matched,1000,2,3,builtin;java"))
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/konveyor/analyzer-lsp/provider"
+	"github.com/konveyor/tackle2-hub/shared/api"
+)
+
+// ProviderTracker tracks the providers needed by the rules
+// and the providers that failed to start.
+type ProviderTracker struct {
+	// Start starts a provider in isolation.
+	Start  func(config provider.Config) (err error)
+	mutex  sync.Mutex
+	needed map[string]bool
+	failed map[string]bool
+}
+
+// Reset the tracker.
+func (r *ProviderTracker) Reset() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.needed = nil
+	r.failed = nil
+}
+
+// Needed sets the providers needed by the rules.
+func (r *ProviderTracker) Needed(names []string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.needed = make(map[string]bool)
+	for _, name := range names {
+		r.needed[name] = true
+	}
+}
+
+// Check the needed providers after the analyzer failed to start them.
+// The analyzer does not report which provider failed (the progress is
+// reported either way) so each is started in isolation and is recorded
+// as failed by its own error.
+func (r *ProviderTracker) Check(configs []provider.Config) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.failed = make(map[string]bool)
+	for _, p := range configs {
+		if p.Name == Builtin || !r.needed[p.Name] {
+			continue
+		}
+		err := r.Start(p)
+		if err != nil {
+			r.failed[p.Name] = true
+			addon.Activity("[PROVIDER] %s failed to start: %s", p.Name, err)
+		}
+	}
+}
+
+// Failed returns the providers that failed to start.
+// When the rules were parsed, the needed providers that failed to
+// start are returned. Otherwise, the remote providers not reachable.
+// The builtin provider is never returned.
+func (r *ProviderTracker) Failed(configs []provider.Config, health *Health) (names []string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for _, p := range configs {
+		if p.Name == Builtin {
+			continue
+		}
+		if r.needed != nil {
+			if r.failed[p.Name] {
+				names = append(names, p.Name)
+			}
+			continue
+		}
+		if p.Address != "" && !health.reachable(p.Address) {
+			names = append(names, p.Name)
+		}
+	}
+	sort.Strings(names)
+	return
+}
+
+// Degraded providers not available.
+type Degraded struct {
+	// Providers (names) not available.
+	Providers []string
+}
+
+// Remove the providers from the configs and record them as not available.
+// Returns ProviderError when no providers remain.
+func (r *Degraded) Remove(configs []provider.Config, names []string) (remaining []provider.Config, err error) {
+	removed := make(map[string]bool)
+	for _, name := range names {
+		removed[name] = true
+	}
+	for _, p := range configs {
+		if !removed[p.Name] {
+			remaining = append(remaining, p)
+			continue
+		}
+		r.Providers = append(r.Providers, p.Name)
+		addon.Error(api.TaskError{
+			Severity: SeverityWarning,
+			Description: fmt.Sprintf(
+				"[PROVIDER] %s not available. Analysis continued without it.",
+				p.Name),
+		})
+	}
+	sort.Strings(r.Providers)
+	if len(remaining) == 0 {
+		err = &ProviderError{Providers: names}
+	}
+	return
+}
+
+// Skipped returns the rules (ruleset.ruleid => reason) that
+// need an unavailable provider.
+func (r *Degraded) Skipped(paths []string) (skipped map[string]string, err error) {
+	skipped = make(map[string]string)
+	if len(r.Providers) == 0 {
+		return
+	}
+	files := RuleFiles{Paths: paths}
+	rules, err := files.Rules()
+	if err != nil {
+		return
+	}
+	for _, rule := range rules {
+		for _, name := range r.Providers {
+			if rule.Conditions[name] > 0 {
+				skipped[rule.Key()] = fmt.Sprintf("provider: %s not available.", name)
+				break
+			}
+		}
+	}
+	return
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"errors"
+	"net"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/konveyor/analyzer-lsp/provider"
+	"github.com/onsi/gomega"
+)
+
+func TestDegraded(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	useFakeHub(t, &FakeHub{})
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	g.Expect(err).To(gomega.BeNil())
+	_ = closed.Close()
+	java := provider.Config{Name: "java"}
+	dotnet := provider.Config{Name: "dotnet", Address: closed.Addr().String()}
+	builtin := provider.Config{Name: "builtin"}
+	configs := []provider.Config{builtin, java, dotnet}
+	health := Health{}
+	// tracker (rules not parsed).
+	tracker := ProviderTracker{}
+	g.Expect(tracker.Failed(configs, &health)).To(gomega.Equal([]string{"dotnet"}))
+	// tracker (rules parsed).
+	// The analyzer reports: started provider: java (init failed).
+	tracker.Needed([]string{"builtin", "java", "dotnet"})
+	tracker.Start = func(p provider.Config) (err error) {
+		if p.Name == "java" {
+			err = errors.New("init failed")
+		}
+		return
+	}
+	tracker.Check(configs)
+	g.Expect(tracker.Failed(configs, &health)).To(gomega.Equal([]string{"java"}))
+	tracker.Reset()
+	g.Expect(tracker.Failed([]provider.Config{builtin, java}, &health)).To(gomega.BeEmpty())
+	// remove.
+	degraded := Degraded{}
+	remaining, err := degraded.Remove(configs, []string{"java"})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(remaining)).To(gomega.Equal(2))
+	g.Expect(degraded.Providers).To(gomega.Equal([]string{"java"}))
+	_, err = degraded.Remove([]provider.Config{java}, []string{"java"})
+	g.Expect(errors.Is(err, &ProviderError{})).To(gomega.BeTrue())
+	// skipped.
+	dir := t.TempDir()
+	err = os.WriteFile(path.Join(dir, "ruleset.yaml"), []byte("name: test\n"), 0644)
+	g.Expect(err).To(gomega.BeNil())
+	rules := `
+- ruleID: rule-001
+  when:
+    java.referenced:
+      pattern: org.test.*
+- ruleID: rule-002
+  when:
+    or:
+    - builtin.file:
+        pattern: x.xml
+    - java.dependency:
+        name: junit
+- ruleID: rule-003
+  when:
+    builtin.file:
+      pattern: x.xml
+`
+	err = os.WriteFile(path.Join(dir, "rules.yaml"), []byte(rules), 0644)
+	g.Expect(err).To(gomega.BeNil())
+	skipped, err := degraded.Skipped([]string{dir})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(skipped)).To(gomega.Equal(2))
+	g.Expect(skipped["test.rule-001"]).To(gomega.ContainSubstring("java"))
+	g.Expect(skipped).To(gomega.HaveKey("test.rule-002"))
+}
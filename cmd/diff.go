@@ -0,0 +1,60 @@
+package main
+
+import (
+	"github.com/konveyor/tackle2-addon-analyzer/builder"
+)
+
+// diffAnalysis returns the diff between the previous analysis and
+// the insights reported by this analysis. The diff visits the
+// insights as they are written (or visited).
+// Must be called before the analysis is uploaded.
+func diffAnalysis(appId uint) (diff *builder.Diff, err error) {
+	previous, err := previousAnalysis(appId)
+	if err != nil {
+		return
+	}
+	if previous == nil {
+		addon.Activity("[DIFF] previous analysis not found.")
+	} else {
+		addon.Activity(
+			"[DIFF] comparing with analysis (id=%d).",
+			previous.ID)
+	}
+	diff = builder.NewDiff(previous)
+	return
+}
+
+// attachDiff builds the diff once the insights have been
+// walked. The diff is reported in the activity and attached
+// to the task.
+func attachDiff(diff *builder.Diff) (err error) {
+	diff.Build()
+	report := &diff.Report
+	for _, r := range report.Rules {
+		if len(r.New)+len(r.Fixed) == 0 {
+			continue
+		}
+		addon.Activity(
+			"[DIFF] %s/%s: new=%d fixed=%d unchanged=%d",
+			r.RuleSet,
+			r.Rule,
+			len(r.New),
+			len(r.Fixed),
+			len(r.Unchanged))
+	}
+	addon.Activity(
+		"[DIFF] incidents: new=%d fixed=%d unchanged=%d",
+		report.Summary.New,
+		report.Summary.Fixed,
+		report.Summary.Unchanged)
+	err = diff.Write()
+	if err != nil {
+		return
+	}
+	f, err := hubApi.FilePost(diff.Path)
+	if err != nil {
+		return
+	}
+	addon.Attach(f)
+	return
+}
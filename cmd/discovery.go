@@ -0,0 +1,117 @@
+package main
+
+import (
+	"slices"
+	"sort"
+
+	liberr "github.com/jortel/go-utils/error"
+	"github.com/konveyor/analyzer-lsp/core"
+	"github.com/konveyor/analyzer-lsp/engine/labels"
+	"github.com/konveyor/analyzer-lsp/provider"
+	"github.com/konveyor/tackle2-addon-analyzer/builder"
+	"github.com/konveyor/tackle2-hub/shared/api"
+)
+
+var (
+	// DiscoverySource the source of facts reported by discovery.
+	DiscoverySource = Source + ":discovery"
+	// DiscoveryLabels the labels of the rules run by discovery.
+	DiscoveryLabels = []string{
+		KonveyorIO + "/include=always",
+		"discovery",
+	}
+	// DiscoveryIncidentLimit the maximum number of incidents
+	// reported by each discovery rule. Discovery only needs
+	// enough incidents for the facts and tag evidence.
+	DiscoveryIncidentLimit = 10
+)
+
+// Discovery fast path.
+// Only the rules labeled for discovery and the providers
+// they need are run. Dependencies are not resolved.
+type Discovery struct {
+	*Data
+}
+
+// ToOptions returns the analyzer options.
+// The discovery label selector replaces the rules and scope selectors.
+func (r *Discovery) ToOptions() (options []core.AnalyzerOption) {
+	selector := r.selector()
+	addon.Activity("[DISCOVERY] using label selector: %s", selector)
+	options = append(
+		options,
+		core.WithRuleFilepaths(r.Rules.rules),
+		core.WithLabelSelector(selector),
+		core.WithDependencyRulesDisabled(),
+		core.WithAnalysisMode(string(provider.SourceOnlyAnalysisMode)),
+		core.WithIncidentLimit(DiscoveryIncidentLimit))
+	return
+}
+
+// Providers returns the provider configs needed by the discovery rules.
+// The builtin provider is always needed.
+func (r *Discovery) Providers(configs []provider.Config) (needed []provider.Config, err error) {
+	selector, err := labels.NewLabelSelector[*ruleLabels](r.selector(), nil)
+	if err != nil {
+		err = liberr.Wrap(err)
+		return
+	}
+	files := RuleFiles{Paths: r.Rules.rules}
+	rules, err := files.Rules()
+	if err != nil {
+		return
+	}
+	names := map[string]bool{Builtin: true}
+	rulesets := map[string]RuleSetFile{}
+	for _, rule := range rules {
+		ruleset, found := rulesets[rule.Dir]
+		if !found {
+			ruleset = files.RuleSet(rule.Dir)
+			rulesets[rule.Dir] = ruleset
+		}
+		x := ruleLabels(slices.Concat(rule.Labels, ruleset.Labels))
+		if ok, _ := selector.Matches(&x); !ok {
+			continue
+		}
+		for name := range rule.Conditions {
+			names[name] = true
+		}
+	}
+	var skipped []string
+	for _, p := range configs {
+		if names[p.Name] {
+			needed = append(needed, p)
+		} else {
+			skipped = append(skipped, p.Name)
+		}
+	}
+	sort.Strings(skipped)
+	if len(skipped) > 0 {
+		addon.Activity("[DISCOVERY] providers not needed: %v", skipped)
+	}
+	return
+}
+
+// Facts returns the facts reported by discovery.
+func (r *Discovery) Facts(insights *builder.Insights) (facts api.Map) {
+	all := insights.Facts()
+	facts = api.Map{}
+	for _, key := range []string{builder.FactTechnologies, builder.FactCapabilities} {
+		facts[key] = all[key]
+	}
+	return
+}
+
+// selector returns the discovery label selector.
+func (r *Discovery) selector() (selector string) {
+	ruleSelector := RuleSelector{Included: DiscoveryLabels}
+	selector = ruleSelector.String()
+	return
+}
+
+// ruleLabels the (effective) labels of a rule.
+type ruleLabels []string
+
+func (r *ruleLabels) GetLabels() []string {
+	return *r
+}
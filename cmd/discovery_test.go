@@ -0,0 +1,151 @@
+package main
+
+import (
+	"net"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/konveyor/analyzer-lsp/provider"
+	"github.com/konveyor/tackle2-addon-analyzer/builder"
+	"github.com/konveyor/tackle2-hub/shared/api"
+	"github.com/onsi/gomega"
+)
+
+func TestDiscovery(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	tmp := useDirs(t)
+	write := func(p, content string) (fp string) {
+		fp = path.Join(tmp, "input", p)
+		err := os.MkdirAll(path.Dir(fp), 0755)
+		g.Expect(err).To(gomega.BeNil())
+		err = os.WriteFile(fp, []byte(content), 0644)
+		g.Expect(err).To(gomega.BeNil())
+		return
+	}
+	write("src/a.txt", "hello TODO world\n")
+	rules := write(
+		"rules.yaml",
+		`
+- ruleID: discovery-00001
+  labels:
+  - konveyor.io/include=always
+  - discovery
+  tag:
+  - Language=Text
+  when:
+    builtin.file:
+      pattern: a.txt
+- ruleID: todo-00001
+  labels:
+  - konveyor.io/target=demo
+  tag:
+  - Demo=Todo
+  when:
+    builtin.filecontent:
+      pattern: TODO
+- ruleID: java-00001
+  labels:
+  - konveyor.io/target=demo
+  when:
+    java.referenced:
+      pattern: org.demo.*
+  message: java
+`)
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	g.Expect(err).To(gomega.BeNil())
+	_ = closed.Close()
+	fake := &FakeHub{
+		Data: api.Map{
+			"mode":   api.Map{"discovery": true, "withDeps": true},
+			"tagger": api.Map{"enabled": true},
+			"health": api.Map{"timeout": 1},
+			"rules": api.Map{
+				"labels": api.Map{
+					"included": []string{"konveyor.io/target=demo"},
+				},
+			},
+		},
+		App: api.Application{
+			Resource: api.Resource{ID: 1},
+			Name:     "demo",
+			Repository: &api.Repository{
+				URL: "https://git.example.com/demo.git",
+			},
+		},
+		AddonDef: api.Addon{
+			Name: "analyzer",
+			Extensions: []api.Extension{
+				{
+					Name:  "builtin",
+					Addon: "analyzer",
+					Metadata: api.Map{
+						"provider": api.Map{
+							"name": "builtin",
+							"initConfig": []api.Map{
+								{"location": "$(builtin.location)"},
+							},
+						},
+					},
+				},
+				{
+					Name:  "java",
+					Addon: "analyzer",
+					Metadata: api.Map{
+						"provider": api.Map{
+							"name":    "java",
+							"address": closed.Addr().String(),
+							"initConfig": []api.Map{
+								{"location": "$(builtin.location)"},
+							},
+						},
+					},
+				},
+			},
+		},
+		RuleSetList: []api.RuleSet{
+			{
+				Resource: api.Resource{ID: 1},
+				Name:     "demo",
+				Rules: []api.Rule{
+					{
+						Name:   "rules.yaml",
+						Labels: []string{"konveyor.io/target=demo"},
+						File:   &api.Ref{ID: 1, Name: "rules.yaml"},
+					},
+				},
+			},
+		},
+		Files: map[uint]string{1: rules},
+		Repositories: map[string]string{
+			"https://git.example.com/demo.git": path.Join(tmp, "input", "src"),
+		},
+		nextId: 100,
+	}
+	useFakeHub(t, fake)
+
+	err = run()
+	g.Expect(err).To(gomega.BeNil())
+	// not analyzed.
+	g.Expect(fake.Uploaded).To(gomega.BeEmpty())
+	// providers.
+	g.Expect(fake.Posted["settings.yaml"]).NotTo(gomega.ContainSubstring("name: java"))
+	g.Expect(fake.Posted["settings.yaml"]).To(gomega.ContainSubstring("analysisMode: source-only"))
+	// tags.
+	g.Expect(fake.TagNames(Source)).To(gomega.Equal([]string{"Language=Text"}))
+	// facts.
+	facts := fake.Facts[DiscoverySource]
+	g.Expect(facts).To(gomega.HaveKey(builder.FactTechnologies))
+	g.Expect(facts[builder.FactTechnologies]).To(gomega.HaveKeyWithValue("Language", []string{"Text"}))
+	g.Expect(fake.Facts).NotTo(gomega.HaveKey(Source))
+	// builtin mode (source-only) with deps and override.
+	settings := Settings{}
+	md := &Metadata{}
+	md.Provider.InitConfig = []provider.InitConfig{{}}
+	md.Override.Mode = provider.FullAnalysisMode
+	mode := Mode{Discovery: true, WithDeps: true}
+	builtin, err := settings.injectBuiltins(md, &mode, "/app")
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(builtin[BuiltinMode]).To(gomega.Equal(string(provider.SourceOnlyAnalysisMode)))
+	g.Expect(md.Provider.InitConfig[0].AnalysisMode).To(gomega.BeEmpty())
+}
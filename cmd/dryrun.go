@@ -0,0 +1,200 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Redacted replaces secrets in the dry-run report.
+const Redacted = "*****"
+
+// SecretKeys (substrings) of (lower case) keys with secret values.
+var SecretKeys = []string{
+	"password",
+	"passwd",
+	"secret",
+	"token",
+	"credential",
+	"key",
+}
+
+// UserInfoRegex matches the user info in URLs.
+var UserInfoRegex = regexp.MustCompile(`://[^/@\s]+@`)
+
+// DryRunReport resolved rules and configuration.
+type DryRunReport struct {
+	Discovery        bool            `yaml:"discovery,omitempty"`
+	Locations        []string        `yaml:"locations"`
+	RuleDirs         []string        `yaml:"ruleDirs"`
+	RuleSets         []DryRunRuleSet `yaml:"ruleSets"`
+	LabelSelector    string          `yaml:"labelSelector,omitempty"`
+	DepLabelSelector string          `yaml:"depLabelSelector,omitempty"`
+	IncidentSelector string          `yaml:"incidentSelector,omitempty"`
+	Providers        any             `yaml:"providers"`
+}
+
+// DryRunRuleSet resolved ruleset.
+type DryRunRuleSet struct {
+	Name   string       `yaml:"name"`
+	Dir    string       `yaml:"dir"`
+	Labels []string     `yaml:"labels,omitempty"`
+	Rules  []DryRunRule `yaml:"rules"`
+}
+
+// DryRunRule resolved rule.
+type DryRunRule struct {
+	ID     string   `yaml:"id"`
+	Labels []string `yaml:"labels,omitempty"`
+}
+
+// DryRun resolves the rules and configuration without analyzing.
+type DryRun struct {
+	*Data
+}
+
+// Run the dry-run.
+// In discovery mode, the discovery label selector and the
+// providers needed by the discovery rules are reported.
+// The report is attached.
+func (r *DryRun) Run() (err error) {
+	settings := Settings{}
+	err = settings.Build(&r.Mode)
+	if err != nil {
+		return
+	}
+	report := DryRunReport{
+		Discovery: r.Mode.Discovery,
+		Locations: r.Mode.Locations(),
+		RuleDirs:  r.Rules.rules,
+	}
+	if r.Mode.Discovery {
+		discovery := Discovery{Data: r.Data}
+		report.LabelSelector = discovery.selector()
+		settings.Configs, err = discovery.Providers(settings.Configs)
+		if err != nil {
+			return
+		}
+	} else {
+		report.LabelSelector = r.Rules.getSelector()
+		report.DepLabelSelector = r.Scope.depLabelSelector(r.Mode)
+		report.IncidentSelector = r.Scope.incidentSelector()
+	}
+	report.RuleSets, err = r.ruleSets()
+	if err != nil {
+		return
+	}
+	report.Providers, err = r.redacted(settings)
+	if err != nil {
+		return
+	}
+	b, err := yaml.Marshal(report)
+	if err != nil {
+		return
+	}
+	p := path.Join(Dir, "dryrun.yaml")
+	err = os.WriteFile(p, b, 0644)
+	if err != nil {
+		return
+	}
+	f, err := hubApi.FilePost(p)
+	if err != nil {
+		return
+	}
+	addon.Attach(f)
+	addon.Activity(
+		"[DRYRUN] resolved %d rulesets. Analysis not performed.",
+		len(report.RuleSets))
+	return
+}
+
+// ruleSets returns the resolved rulesets (by directory).
+func (r *DryRun) ruleSets() (list []DryRunRuleSet, err error) {
+	files := RuleFiles{Paths: r.Rules.rules}
+	rules, err := files.Rules()
+	if err != nil {
+		return
+	}
+	index := make(map[string]int)
+	for _, rule := range rules {
+		i, found := index[rule.Dir]
+		if !found {
+			ruleset := files.RuleSet(rule.Dir)
+			i = len(list)
+			index[rule.Dir] = i
+			list = append(
+				list,
+				DryRunRuleSet{
+					Name:   ruleset.Name,
+					Dir:    rule.Dir,
+					Labels: ruleset.Labels,
+				})
+		}
+		list[i].Rules = append(
+			list[i].Rules,
+			DryRunRule{
+				ID:     rule.ID,
+				Labels: rule.Labels,
+			})
+	}
+	return
+}
+
+// redacted returns the provider settings with secrets redacted.
+func (r *DryRun) redacted(settings Settings) (providers any, err error) {
+	b, err := yaml.Marshal(settings.Configs)
+	if err != nil {
+		return
+	}
+	err = yaml.Unmarshal(b, &providers)
+	if err != nil {
+		return
+	}
+	providers = r.redact("", providers)
+	return
+}
+
+// redact secrets in the node.
+// Values of secret keys and the user info in URLs are redacted.
+func (r *DryRun) redact(key string, node any) (redacted any) {
+	switch node := node.(type) {
+	case map[any]any:
+		for k, v := range node {
+			node[k] = r.redact(fmt.Sprintf("%v", k), v)
+		}
+		redacted = node
+	case []any:
+		for i, v := range node {
+			node[i] = r.redact(key, v)
+		}
+		redacted = node
+	case string:
+		if r.secret(key) && node != "" {
+			redacted = Redacted
+		} else {
+			redacted = UserInfoRegex.ReplaceAllString(node, "://"+Redacted+"@")
+		}
+	default:
+		redacted = node
+		if r.secret(key) && node != nil {
+			redacted = Redacted
+		}
+	}
+	return
+}
+
+// secret returns true when the key has a secret value.
+func (r *DryRun) secret(key string) (b bool) {
+	key = strings.ToLower(key)
+	for _, s := range SecretKeys {
+		if strings.Contains(key, s) {
+			b = true
+			break
+		}
+	}
+	return
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	"github.com/konveyor/tackle2-hub/shared/api"
+	"github.com/onsi/gomega"
+	"gopkg.in/yaml.v2"
+)
+
+func TestDryRun(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	tmp := useDirs(t)
+	write := func(p, content string) (fp string) {
+		fp = path.Join(tmp, "input", p)
+		err := os.MkdirAll(path.Dir(fp), 0755)
+		g.Expect(err).To(gomega.BeNil())
+		err = os.WriteFile(fp, []byte(content), 0644)
+		g.Expect(err).To(gomega.BeNil())
+		return
+	}
+	write("src/a.txt", "hello TODO world\n")
+	rules := write(
+		"rules.yaml",
+		`
+- ruleID: todo-00001
+  labels:
+  - konveyor.io/target=demo
+  when:
+    builtin.filecontent:
+      pattern: TODO
+  message: TODO found
+`)
+	fake := &FakeHub{
+		Data: api.Map{
+			"mode": api.Map{"dryRun": true},
+			"scope": api.Map{
+				"packages": api.Map{"included": []string{"org.demo"}},
+			},
+			"rules": api.Map{
+				"labels": api.Map{
+					"included": []string{"konveyor.io/target=demo"},
+				},
+			},
+		},
+		App: api.Application{
+			Resource: api.Resource{ID: 1},
+			Name:     "demo",
+			Repository: &api.Repository{
+				URL: "https://git.example.com/demo.git",
+			},
+		},
+		AddonDef: api.Addon{
+			Name: "analyzer",
+			Extensions: []api.Extension{
+				{
+					Name:  "builtin",
+					Addon: "analyzer",
+					Metadata: api.Map{
+						"provider": api.Map{
+							"name": "builtin",
+							"initConfig": []api.Map{
+								{
+									"location": "$(builtin.location)",
+									"providerSpecificConfig": api.Map{
+										"password": "s3cret",
+										"url":      "https://user:pw@rules.example.com/x",
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		RuleSetList: []api.RuleSet{
+			{
+				Resource: api.Resource{ID: 1},
+				Name:     "demo",
+				Rules: []api.Rule{
+					{
+						Name:   "rules.yaml",
+						Labels: []string{"konveyor.io/target=demo"},
+						File:   &api.Ref{ID: 1, Name: "rules.yaml"},
+					},
+				},
+			},
+		},
+		Files: map[uint]string{1: rules},
+		Repositories: map[string]string{
+			"https://git.example.com/demo.git": path.Join(tmp, "input", "src"),
+		},
+		nextId: 100,
+	}
+	useFakeHub(t, fake)
+
+	err := run()
+	g.Expect(err).To(gomega.BeNil())
+	// not analyzed.
+	g.Expect(fake.Uploaded).To(gomega.BeEmpty())
+	g.Expect(fake.Posted).NotTo(gomega.HaveKey("settings.yaml"))
+	// report.
+	g.Expect(fake.Posted).To(gomega.HaveKey("dryrun.yaml"))
+	report := DryRunReport{}
+	err = yaml.Unmarshal([]byte(fake.Posted["dryrun.yaml"]), &report)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(report.LabelSelector).To(gomega.ContainSubstring("konveyor.io/target=demo"))
+	g.Expect(report.IncidentSelector).To(gomega.ContainSubstring("package=org.demo"))
+	var ruleIds []string
+	for _, ruleset := range report.RuleSets {
+		for _, rule := range ruleset.Rules {
+			ruleIds = append(ruleIds, rule.ID)
+		}
+	}
+	g.Expect(ruleIds).To(gomega.ContainElement("todo-00001"))
+	g.Expect(fake.Posted["dryrun.yaml"]).To(gomega.ContainSubstring("location: " + path.Join(tmp, "shared", "source", "demo")))
+	g.Expect(fake.Posted["dryrun.yaml"]).NotTo(gomega.ContainSubstring("s3cret"))
+	g.Expect(fake.Posted["dryrun.yaml"]).NotTo(gomega.ContainSubstring("user:pw"))
+	g.Expect(fake.Posted["dryrun.yaml"]).To(gomega.ContainSubstring("https://*****@rules.example.com/x"))
+	// discovery.
+	fake.Data = api.Map{
+		"mode": api.Map{"dryRun": true, "discovery": true},
+		"scope": api.Map{
+			"packages": api.Map{"included": []string{"org.demo"}},
+		},
+	}
+	err = run()
+	g.Expect(err).To(gomega.BeNil())
+	report = DryRunReport{}
+	err = yaml.Unmarshal([]byte(fake.Posted["dryrun.yaml"]), &report)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(report.Discovery).To(gomega.BeTrue())
+	g.Expect(report.LabelSelector).To(gomega.ContainSubstring("discovery"))
+	g.Expect(report.LabelSelector).NotTo(gomega.ContainSubstring("konveyor.io/target=demo"))
+	g.Expect(report.IncidentSelector).To(gomega.BeEmpty())
+	g.Expect(fake.Posted["dryrun.yaml"]).To(gomega.ContainSubstring("name: builtin"))
+}
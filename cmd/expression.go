@@ -0,0 +1,270 @@
+package main
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// TermRegex <key>:-<default> | <key>:?<message>
+var (
+	TermRegex = regexp.MustCompile(`^\s*([\w.\-/]+)\s*(:-|:\?)(.*)$`)
+)
+
+// ExpressionError reports expression errors.
+type ExpressionError struct {
+	Expression string
+	Reason     string
+}
+
+func (e *ExpressionError) Error() (s string) {
+	return fmt.Sprintf(
+		"Expression: '$(%s)' failed: %s",
+		e.Expression,
+		e.Reason)
+}
+
+func (e *ExpressionError) Is(err error) (matched bool) {
+	var inst *ExpressionError
+	matched = errors.As(err, &inst)
+	return
+}
+
+// Expression evaluated within $(...).
+// Format: <term> [| <function> [arg]...]...
+// Terms:
+//   - key: the value of the key.
+//   - 'literal': the literal (quoted) string.
+//   - key:-default: the value of the key or the default when missing or empty.
+//   - key:?message: the value of the key. Fails with the message when missing.
+//   - cond ? a : b: a when cond is true, else b. The cond may be `x`, `!x`,
+//     `x == y` or `x != y` where operands are keys or (quoted) literals.
+//
+// Functions:
+//   - lower: lower case.
+//   - upper: upper case.
+//   - base64: base64 encoded.
+//   - join [separator]: join a list. The default separator is `,`.
+type Expression struct {
+	dict map[string]any
+	// missing keys referenced by the expression.
+	missing []string
+}
+
+// Eval evaluates the expression.
+func (e *Expression) Eval(s string) (v any, err error) {
+	defer func() {
+		if err != nil {
+			err = &ExpressionError{
+				Expression: s,
+				Reason:     err.Error(),
+			}
+		}
+	}()
+	part := e.split(s, "|")
+	v, err = e.term(part[0])
+	if err != nil {
+		return
+	}
+	for _, fn := range part[1:] {
+		v, err = e.call(fn, v)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+// term evaluates a term.
+func (e *Expression) term(s string) (v any, err error) {
+	match := TermRegex.FindStringSubmatch(s)
+	if len(match) == 4 {
+		key := match[1]
+		arg := e.unquote(strings.TrimSpace(match[3]))
+		v = e.dict[key]
+		switch match[2] {
+		case ":-":
+			if e.empty(v) {
+				v = arg
+			}
+		case ":?":
+			if v == nil {
+				if arg == "" {
+					arg = "required"
+				}
+				err = fmt.Errorf("key: '%s' %s", key, arg)
+			}
+		}
+		return
+	}
+	part := e.split(s, "?")
+	if len(part) > 1 {
+		then := strings.Join(part[1:], "?")
+		choice := e.split(then, ":")
+		if len(choice) != 2 {
+			err = errors.New("expected: cond ? a : b")
+			return
+		}
+		if e.cond(part[0]) {
+			v = e.operand(choice[0])
+		} else {
+			v = e.operand(choice[1])
+		}
+		return
+	}
+	v = e.operand(s)
+	if v == nil {
+		key := strings.TrimSpace(s)
+		if _, found := e.dict[key]; !found {
+			e.missing = append(e.missing, key)
+		}
+	}
+	return
+}
+
+// cond evaluates a condition.
+func (e *Expression) cond(s string) (b bool) {
+	for _, op := range []string{"==", "!="} {
+		part := e.split(s, op)
+		if len(part) == 2 {
+			x := e.string(e.operand(part[0]))
+			y := e.string(e.operand(part[1]))
+			b = (x == y) == (op == "==")
+			return
+		}
+	}
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "!") {
+		b = e.empty(e.operand(s[1:]))
+		return
+	}
+	b = !e.empty(e.operand(s))
+	return
+}
+
+// call a function.
+func (e *Expression) call(s string, in any) (v any, err error) {
+	args := e.fields(s)
+	if len(args) == 0 {
+		err = errors.New("function expected")
+		return
+	}
+	name := args[0]
+	args = args[1:]
+	switch name {
+	case "lower":
+		v = strings.ToLower(e.string(in))
+	case "upper":
+		v = strings.ToUpper(e.string(in))
+	case "base64":
+		v = base64.StdEncoding.EncodeToString([]byte(e.string(in)))
+	case "join":
+		separator := ","
+		if len(args) > 0 {
+			separator = args[0]
+		}
+		var list []string
+		switch x := in.(type) {
+		case []any:
+			for _, item := range x {
+				list = append(list, e.string(item))
+			}
+		case []string:
+			list = x
+		default:
+			if !e.empty(x) {
+				list = append(list, e.string(x))
+			}
+		}
+		v = strings.Join(list, separator)
+	default:
+		err = fmt.Errorf("function: '%s' not-supported", name)
+	}
+	return
+}
+
+// operand returns the value of a key or (quoted) literal.
+func (e *Expression) operand(s string) (v any) {
+	s = strings.TrimSpace(s)
+	unquoted := e.unquote(s)
+	if unquoted != s || s == "''" || s == `""` {
+		v = unquoted
+		return
+	}
+	v = e.dict[s]
+	return
+}
+
+// empty returns true when the value is nil, empty or false.
+func (e *Expression) empty(v any) (b bool) {
+	switch x := v.(type) {
+	case nil:
+		b = true
+	case bool:
+		b = !x
+	case string:
+		b = x == "" || x == "false"
+	case int:
+		b = x == 0
+	case float64:
+		b = x == 0
+	}
+	return
+}
+
+// string returns the string representation of a value.
+func (e *Expression) string(v any) (s string) {
+	if v != nil {
+		s = fmt.Sprintf("%v", v)
+	}
+	return
+}
+
+// unquote returns the string with (matched) quotes removed.
+func (e *Expression) unquote(s string) (unquoted string) {
+	unquoted = s
+	if len(s) < 2 {
+		return
+	}
+	q := s[0]
+	if (q == '\'' || q == '"') && s[len(s)-1] == q {
+		unquoted = s[1 : len(s)-1]
+	}
+	return
+}
+
+// split the string on the (unquoted) separator.
+func (e *Expression) split(s, separator string) (part []string) {
+	var quote byte
+	begin := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case strings.HasPrefix(s[i:], separator):
+			part = append(part, s[begin:i])
+			i += len(separator) - 1
+			begin = i + 1
+		}
+	}
+	part = append(part, s[begin:])
+	return
+}
+
+// fields returns the (unquoted) space separated fields.
+func (e *Expression) fields(s string) (fields []string) {
+	for _, f := range e.split(strings.TrimSpace(s), " ") {
+		if f == "" {
+			continue
+		}
+		fields = append(fields, e.unquote(f))
+	}
+	return
+}
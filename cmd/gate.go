@@ -0,0 +1,99 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	output "github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+	"github.com/konveyor/tackle2-addon-analyzer/builder"
+	"github.com/konveyor/tackle2-hub/shared/api"
+)
+
+// GateError reports the quality gate failed.
+type GateError struct {
+	Violations []api.TaskError
+}
+
+func (e *GateError) Error() (s string) {
+	return fmt.Sprintf(
+		"Quality gate failed: %d violations.",
+		len(e.Violations))
+}
+
+func (e *GateError) Is(err error) (matched bool) {
+	var inst *GateError
+	matched = errors.As(err, &inst)
+	return
+}
+
+// Gate quality gate settings.
+// Thresholds not specified are not evaluated.
+type Gate struct {
+	// MaxEffort the maximum total effort.
+	MaxEffort *int `json:"maxEffort,omitempty" yaml:",omitempty"`
+	// MaxMandatory the maximum number of mandatory incidents.
+	MaxMandatory *int `json:"maxMandatory,omitempty" yaml:",omitempty"`
+	// Categories forbidden (incident) categories.
+	Categories []string `json:"categories,omitempty" yaml:",omitempty"`
+	// MaxNew the maximum number of new incidents
+	// relative to the previous analysis.
+	MaxNew *int `json:"maxNew,omitempty" yaml:",omitempty"`
+}
+
+// NeedsDiff returns true when the diff with the previous
+// analysis is needed to evaluate the gate.
+func (r *Gate) NeedsDiff() (b bool) {
+	b = r.MaxNew != nil
+	return
+}
+
+// Evaluate the gate.
+// Returns a GateError when a threshold is exceeded.
+func (r *Gate) Evaluate(insights *builder.Insights, diff *builder.Diff) (err error) {
+	var violations []api.TaskError
+	violated := func(format string, v ...any) {
+		violations = append(
+			violations,
+			api.TaskError{
+				Severity:    "Error",
+				Description: "[GATE] " + fmt.Sprintf(format, v...),
+			})
+	}
+	effort := insights.Effort()["total"]
+	if r.MaxEffort != nil && effort > *r.MaxEffort {
+		violated(
+			"effort: %d exceeds the maximum: %d.",
+			effort,
+			*r.MaxEffort)
+	}
+	incidents := insights.Incidents()
+	mandatory := incidents[string(output.Mandatory)]
+	if r.MaxMandatory != nil && mandatory > *r.MaxMandatory {
+		violated(
+			"mandatory incidents: %d exceeds the maximum: %d.",
+			mandatory,
+			*r.MaxMandatory)
+	}
+	for _, category := range r.Categories {
+		n := incidents[category]
+		if n > 0 {
+			violated(
+				"category: %s is forbidden. found: %d incidents.",
+				category,
+				n)
+		}
+	}
+	if r.MaxNew != nil && diff != nil {
+		n := diff.Report.Summary.New
+		if n > *r.MaxNew {
+			violated(
+				"new incidents: %d exceeds the maximum: %d.",
+				n,
+				*r.MaxNew)
+		}
+	}
+	if len(violations) > 0 {
+		err = &GateError{Violations: violations}
+	}
+	return
+}
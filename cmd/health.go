@@ -0,0 +1,222 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	output "github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+	"github.com/konveyor/analyzer-lsp/provider"
+	"github.com/konveyor/tackle2-hub/shared/api"
+)
+
+// Health defaults.
+const (
+	HealthAttempts = 2
+	HealthTimeout  = 120
+)
+
+// ConnectionErrors (substrings) reported when a provider connection is lost.
+var ConnectionErrors = []string{
+	"code = Unavailable",
+	"connection refused",
+	"connection reset",
+	"transport is closing",
+	"error reading from server",
+}
+
+// ProviderError reports providers not ready or failed.
+type ProviderError struct {
+	Providers []string
+}
+
+func (e *ProviderError) Error() (s string) {
+	return fmt.Sprintf(
+		"Provider(s): %s failed.",
+		strings.Join(e.Providers, ","))
+}
+
+func (e *ProviderError) Is(err error) (matched bool) {
+	var inst *ProviderError
+	matched = errors.As(err, &inst)
+	return
+}
+
+// Health provider health options.
+type Health struct {
+	// Attempts the maximum number of analysis attempts
+	// when a provider fails.
+	Attempts int `json:"attempts"`
+	// Timeout (seconds) to wait for a provider to be ready.
+	Timeout int `json:"timeout"`
+	// Degrade continue with the providers that started
+	// when a provider is not available.
+	Degrade bool `json:"degrade"`
+	//
+	interval time.Duration
+	degraded Degraded
+}
+
+// Probe each (remote) provider address until ready or the timeout.
+// The readiness of each provider is reported.
+func (r *Health) Probe(configs []provider.Config) (err error) {
+	var failed []string
+	for _, p := range configs {
+		if p.Address == "" {
+			addon.Activity("[PROVIDER] %s ready (in-process).", p.Name)
+			continue
+		}
+		mark := time.Now()
+		ready := r.wait(p.Address)
+		if ready {
+			addon.Activity(
+				"[PROVIDER] %s ready. address: %s duration: %s",
+				p.Name,
+				p.Address,
+				time.Since(mark))
+		} else {
+			addon.Error(api.TaskError{
+				Severity: SeverityError,
+				Description: fmt.Sprintf(
+					"[PROVIDER] %s not ready after %ds. address: %s",
+					p.Name,
+					r.timeout(),
+					p.Address),
+			})
+			failed = append(failed, p.Name)
+		}
+	}
+	if len(failed) > 0 {
+		err = &ProviderError{Providers: failed}
+	}
+	return
+}
+
+// Failed returns the (remote) providers that failed during analysis.
+// A failure is detected when the analyzer reported a connection error. The
+// providers not reachable are returned. When all are reachable, the provider
+// was restarted (by the platform) and all remote providers are returned.
+func (r *Health) Failed(configs []provider.Config, results []output.RuleSet, err error) (failed []provider.Config) {
+	var remote []provider.Config
+	for _, p := range configs {
+		if p.Address != "" {
+			remote = append(remote, p)
+		}
+	}
+	if len(remote) == 0 {
+		return
+	}
+	lost := err != nil && r.connectionError(err.Error())
+	for _, ruleset := range results {
+		for _, msg := range ruleset.Errors {
+			if r.connectionError(msg) {
+				lost = true
+				break
+			}
+		}
+	}
+	if !lost {
+		return
+	}
+	for _, p := range remote {
+		if !r.reachable(p.Address) {
+			failed = append(failed, p)
+		}
+	}
+	if len(failed) == 0 {
+		failed = remote
+	}
+	return
+}
+
+// Report the providers failed after all attempts.
+func (r *Health) Report(failed []provider.Config) (err error) {
+	var names []string
+	for _, p := range failed {
+		names = append(names, p.Name)
+		addon.Error(api.TaskError{
+			Severity: SeverityError,
+			Description: fmt.Sprintf(
+				"[PROVIDER] %s failed after %d attempts. address: %s",
+				p.Name,
+				r.attempts(),
+				p.Address),
+		})
+	}
+	sort.Strings(names)
+	err = &ProviderError{Providers: names}
+	return
+}
+
+// Names returns the provider names.
+func (r *Health) Names(configs []provider.Config) (names string) {
+	names = strings.Join(r.names(configs), ",")
+	return
+}
+
+// names returns the list of provider names.
+func (r *Health) names(configs []provider.Config) (names []string) {
+	for _, p := range configs {
+		names = append(names, p.Name)
+	}
+	return
+}
+
+// attempts returns the maximum number of attempts.
+func (r *Health) attempts() (n int) {
+	n = r.Attempts
+	if n < 1 {
+		n = HealthAttempts
+	}
+	return
+}
+
+// timeout returns the probe timeout (seconds).
+func (r *Health) timeout() (n int) {
+	n = r.Timeout
+	if n < 1 {
+		n = HealthTimeout
+	}
+	return
+}
+
+// wait for the address to be reachable.
+func (r *Health) wait(address string) (ready bool) {
+	interval := r.interval
+	if interval == 0 {
+		interval = 2 * time.Second
+	}
+	deadline := time.Now().Add(time.Duration(r.timeout()) * time.Second)
+	for {
+		ready = r.reachable(address)
+		if ready || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(interval)
+	}
+	return
+}
+
+// reachable returns true when the address accepts connections.
+func (r *Health) reachable(address string) (b bool) {
+	conn, err := net.DialTimeout("tcp", address, 2*time.Second)
+	if err == nil {
+		_ = conn.Close()
+		b = true
+	}
+	return
+}
+
+// connectionError returns true when the message reports a lost connection.
+func (r *Health) connectionError(msg string) (b bool) {
+	for _, s := range ConnectionErrors {
+		if strings.Contains(msg, s) {
+			b = true
+			break
+		}
+	}
+	return
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	output "github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+	"github.com/konveyor/analyzer-lsp/provider"
+	"github.com/onsi/gomega"
+)
+
+func TestHealth(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	useFakeHub(t, &FakeHub{})
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	g.Expect(err).To(gomega.BeNil())
+	defer func() {
+		_ = listener.Close()
+	}()
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	g.Expect(err).To(gomega.BeNil())
+	_ = closed.Close()
+	java := provider.Config{Name: "java", Address: listener.Addr().String()}
+	dotnet := provider.Config{Name: "dotnet", Address: closed.Addr().String()}
+	builtin := provider.Config{Name: "builtin"}
+	health := Health{Timeout: 1, interval: 10 * time.Millisecond}
+	// probe.
+	err = health.Probe([]provider.Config{builtin, java})
+	g.Expect(err).To(gomega.BeNil())
+	err = health.Probe([]provider.Config{builtin, java, dotnet})
+	g.Expect(errors.Is(err, &ProviderError{})).To(gomega.BeTrue())
+	// failed.
+	configs := []provider.Config{builtin, java, dotnet}
+	results := []output.RuleSet{
+		{
+			Name:   "test",
+			Errors: map[string]string{"rule-001": "rpc error: code = Unknown desc = bad pattern"},
+		},
+	}
+	failed := health.Failed(configs, results, nil)
+	g.Expect(failed).To(gomega.BeEmpty())
+	results[0].Errors["rule-002"] = "rpc error: code = Unavailable desc = connection refused"
+	failed = health.Failed(configs, results, nil)
+	g.Expect(len(failed)).To(gomega.Equal(1))
+	g.Expect(failed[0].Name).To(gomega.Equal("dotnet"))
+	failed = health.Failed([]provider.Config{builtin}, results, nil)
+	g.Expect(failed).To(gomega.BeEmpty())
+	failed = health.Failed([]provider.Config{java}, nil, errors.New("transport is closing"))
+	g.Expect(len(failed)).To(gomega.Equal(1))
+	// report.
+	err = health.Report(failed)
+	g.Expect(errors.Is(err, &ProviderError{})).To(gomega.BeTrue())
+	g.Expect(err.Error()).To(gomega.ContainSubstring("java"))
+}
+
+func TestRetry(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	useFakeHub(t, &FakeHub{})
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	g.Expect(err).To(gomega.BeNil())
+	defer func() {
+		_ = listener.Close()
+	}()
+	java := provider.Config{Name: "java", Address: listener.Addr().String()}
+	builtin := provider.Config{Name: "builtin"}
+	runs := 0
+	run := func(configs []provider.Config) (results []output.RuleSet, _ map[string][]string, err error) {
+		runs++
+		for _, p := range configs {
+			if p.Name == java.Name {
+				err = errors.New("transport is closing")
+			}
+		}
+		return
+	}
+	// retried until attempts exhausted.
+	analyzer := Analyzer{Data: &Data{}}
+	analyzer.Health = Health{Attempts: 3, Timeout: 1, interval: 10 * time.Millisecond}
+	_, _, err = analyzer.retry([]provider.Config{builtin, java}, run)
+	g.Expect(errors.Is(err, &ProviderError{})).To(gomega.BeTrue())
+	g.Expect(err.Error()).To(gomega.ContainSubstring("java"))
+	g.Expect(runs).To(gomega.Equal(3))
+	// degraded after attempts exhausted.
+	runs = 0
+	analyzer = Analyzer{Data: &Data{}}
+	analyzer.Health = Health{Attempts: 3, Timeout: 1, Degrade: true, interval: 10 * time.Millisecond}
+	_, _, err = analyzer.retry([]provider.Config{builtin, java}, run)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(runs).To(gomega.Equal(4))
+	g.Expect(analyzer.Health.degraded.Providers).To(gomega.Equal([]string{"java"}))
+	// report error kept when no providers remain.
+	runs = 0
+	analyzer = Analyzer{Data: &Data{}}
+	analyzer.Health = Health{Attempts: 2, Timeout: 1, Degrade: true, interval: 10 * time.Millisecond}
+	_, _, err = analyzer.retry([]provider.Config{java}, run)
+	g.Expect(errors.Is(err, &ProviderError{})).To(gomega.BeTrue())
+	g.Expect(err.Error()).To(gomega.ContainSubstring("java"))
+	g.Expect(runs).To(gomega.Equal(2))
+}
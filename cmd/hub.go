@@ -0,0 +1,265 @@
+package main
+
+import (
+	"errors"
+
+	hub "github.com/konveyor/tackle2-hub/shared/addon"
+	"github.com/konveyor/tackle2-hub/shared/addon/scm"
+	"github.com/konveyor/tackle2-hub/shared/api"
+)
+
+// hubApi the hub API used by the addon.
+var hubApi Hub = &AddonHub{}
+
+// Hub the (narrow) hub API used by the addon.
+// Task reporting (activity, errors, attachments) is
+// provided by the addon adapter.
+type Hub interface {
+	// DataWith populates the task data object.
+	DataWith(object any) (err error)
+	// Application returns the application associated with the task.
+	Application() (r *api.Application, err error)
+	// Addon returns the addon (and selected extensions) associated with the task.
+	Addon() (r *api.Addon, err error)
+	// AppIdentity finds the identity (by role/kind) associated
+	// directly or indirectly with the application.
+	AppIdentity(appId uint, kind string) (r *api.Identity, found bool, err error)
+	// Identity returns the (decrypted) identity.
+	Identity(id uint) (r *api.Identity, err error)
+	// Profile returns the analysis profile.
+	Profile(id uint) (r *api.AnalysisProfile, err error)
+	// Target returns the migration target.
+	Target(id uint) (r *api.Target, err error)
+	// RuleSet returns the ruleset.
+	RuleSet(id uint) (r *api.RuleSet, err error)
+	// RuleSets returns all rulesets.
+	RuleSets() (list []api.RuleSet, err error)
+	// Setting populates the setting value.
+	Setting(key string, v any) (err error)
+	// Proxy returns the proxy by kind. Returns nil when not found.
+	Proxy(kind string) (r *api.Proxy, err error)
+	// Repository returns the SCM repository.
+	Repository(destDir string, repository api.Repository, identity *api.Identity) (r scm.SCM, err error)
+	// FileGet downloads a file.
+	FileGet(id uint, destination string) (err error)
+	// FilePost uploads a file.
+	FilePost(path string) (r *api.File, err error)
+	// BucketGet downloads the task bucket content.
+	BucketGet(source, destination string) (err error)
+	// Analysis returns the latest analysis for the application.
+	Analysis(appId uint) (r *api.Analysis, err error)
+	// AnalysisUpload uploads the analysis manifest.
+	AnalysisUpload(appId uint, manifest string) (r *api.Analysis, err error)
+	// FactReplace replaces the application facts by source.
+	FactReplace(appId uint, source string, facts api.Map) (err error)
+	// AppFact finds an application fact by (source:name) key.
+	AppFact(appId uint, key string) (v any, found bool, err error)
+	// TagCategory finds the tag category by name.
+	TagCategory(name string) (r *api.TagCategory, found bool, err error)
+	// TagCategoryList returns all tag categories.
+	TagCategoryList() (list []api.TagCategory, err error)
+	// TagCategoryEnsure ensures the tag category exists.
+	TagCategoryEnsure(r *api.TagCategory) (err error)
+	// TagCategoryUpdate updates the tag category.
+	TagCategoryUpdate(r *api.TagCategory) (err error)
+	// TagList returns all tags.
+	TagList() (list []api.Tag, err error)
+	// TagEnsure ensures the tag exists.
+	TagEnsure(r *api.Tag) (err error)
+	// AppTagList returns the tags associated with the application (all sources).
+	AppTagList(appId uint) (list []api.TagRef, err error)
+	// AppTagReplace replaces the tags associated with the application by source.
+	AppTagReplace(appId uint, source string, ids []uint) (err error)
+}
+
+// AddonHub the hub API provided by the addon adapter.
+type AddonHub struct {
+}
+
+// DataWith populates the task data object.
+func (h *AddonHub) DataWith(object any) (err error) {
+	err = addon.DataWith(object)
+	return
+}
+
+// Application returns the application associated with the task.
+func (h *AddonHub) Application() (r *api.Application, err error) {
+	r, err = addon.Task.Application()
+	return
+}
+
+// Addon returns the addon (and selected extensions) associated with the task.
+// The extensions are injected.
+func (h *AddonHub) Addon() (r *api.Addon, err error) {
+	r, err = addon.Addon(true)
+	return
+}
+
+// AppIdentity finds the identity (by role/kind) associated
+// directly or indirectly with the application.
+func (h *AddonHub) AppIdentity(appId uint, kind string) (r *api.Identity, found bool, err error) {
+	r, found, err =
+		addon.Application.Select(appId).Identity.
+			Decrypted().
+			Search().
+			Direct(kind).
+			Indirect(kind).
+			Find()
+	return
+}
+
+// Identity returns the (decrypted) identity.
+func (h *AddonHub) Identity(id uint) (r *api.Identity, err error) {
+	r, err = addon.Identity.Decrypted().Get(id)
+	return
+}
+
+// Profile returns the analysis profile.
+func (h *AddonHub) Profile(id uint) (r *api.AnalysisProfile, err error) {
+	r, err = addon.AnalysisProfile.Get(id)
+	return
+}
+
+// Target returns the migration target.
+func (h *AddonHub) Target(id uint) (r *api.Target, err error) {
+	r, err = addon.Target.Get(id)
+	return
+}
+
+// RuleSet returns the ruleset.
+func (h *AddonHub) RuleSet(id uint) (r *api.RuleSet, err error) {
+	r, err = addon.RuleSet.Get(id)
+	return
+}
+
+// RuleSets returns all rulesets.
+func (h *AddonHub) RuleSets() (list []api.RuleSet, err error) {
+	list, err = addon.RuleSet.List()
+	return
+}
+
+// Setting populates the setting value.
+func (h *AddonHub) Setting(key string, v any) (err error) {
+	err = addon.Setting.Get(key, v)
+	return
+}
+
+// Proxy returns the proxy by kind. Returns nil when not found.
+func (h *AddonHub) Proxy(kind string) (r *api.Proxy, err error) {
+	r, err = addon.Proxy.Find(kind)
+	return
+}
+
+// Repository returns the SCM repository.
+func (h *AddonHub) Repository(destDir string, repository api.Repository, identity *api.Identity) (r scm.SCM, err error) {
+	r, err = scm.New(destDir, repository, identity)
+	return
+}
+
+// FileGet downloads a file.
+func (h *AddonHub) FileGet(id uint, destination string) (err error) {
+	err = addon.File.Get(id, destination)
+	return
+}
+
+// FilePost uploads a file.
+func (h *AddonHub) FilePost(path string) (r *api.File, err error) {
+	r, err = addon.File.Post(path)
+	return
+}
+
+// BucketGet downloads the task bucket content.
+func (h *AddonHub) BucketGet(source, destination string) (err error) {
+	bucket := addon.Bucket()
+	err = bucket.Get(source, destination)
+	return
+}
+
+// Analysis returns the latest analysis for the application.
+func (h *AddonHub) Analysis(appId uint) (r *api.Analysis, err error) {
+	r, err = addon.Application.Select(appId).Analysis.Get()
+	return
+}
+
+// AnalysisUpload uploads the analysis manifest.
+func (h *AddonHub) AnalysisUpload(appId uint, manifest string) (r *api.Analysis, err error) {
+	r, err = addon.Application.
+		Select(appId).
+		Analysis.
+		Upload(manifest, api.MIMEYAML)
+	return
+}
+
+// FactReplace replaces the application facts by source.
+func (h *AddonHub) FactReplace(appId uint, source string, facts api.Map) (err error) {
+	err = addon.Application.Select(appId).
+		Fact.
+		Source(source).
+		Replace(facts)
+	return
+}
+
+// AppFact finds an application fact by (source:name) key.
+func (h *AddonHub) AppFact(appId uint, key string) (v any, found bool, err error) {
+	fk := api.FactKey(key)
+	err = addon.Application.Select(appId).
+		Fact.
+		Source(fk.Source()).
+		Get(fk.Name(), &v)
+	if err != nil {
+		if errors.Is(err, &hub.NotFound{}) {
+			err = nil
+		}
+		return
+	}
+	found = true
+	return
+}
+
+// TagCategory finds the tag category by name.
+func (h *AddonHub) TagCategory(name string) (r *api.TagCategory, found bool, err error) {
+	r, found, err = addon.TagCategory.Find(name)
+	return
+}
+
+// TagCategoryList returns all tag categories.
+func (h *AddonHub) TagCategoryList() (list []api.TagCategory, err error) {
+	list, err = addon.TagCategory.List()
+	return
+}
+
+// TagCategoryEnsure ensures the tag category exists.
+func (h *AddonHub) TagCategoryEnsure(r *api.TagCategory) (err error) {
+	err = addon.TagCategory.Ensure(r)
+	return
+}
+
+// TagCategoryUpdate updates the tag category.
+func (h *AddonHub) TagCategoryUpdate(r *api.TagCategory) (err error) {
+	err = addon.TagCategory.Update(r)
+	return
+}
+
+// TagList returns all tags.
+func (h *AddonHub) TagList() (list []api.Tag, err error) {
+	list, err = addon.Tag.List()
+	return
+}
+
+// TagEnsure ensures the tag exists.
+func (h *AddonHub) TagEnsure(r *api.Tag) (err error) {
+	err = addon.Tag.Ensure(r)
+	return
+}
+
+// AppTagList returns the tags associated with the application (all sources).
+func (h *AddonHub) AppTagList(appId uint) (list []api.TagRef, err error) {
+	list, err = addon.Application.Select(appId).Tag.List()
+	return
+}
+
+// AppTagReplace replaces the tags associated with the application by source.
+func (h *AddonHub) AppTagReplace(appId uint, source string, ids []uint) (err error) {
+	err = addon.Application.Select(appId).Tag.Source(source).Replace(ids)
+	return
+}
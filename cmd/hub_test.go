@@ -0,0 +1,442 @@
+package main
+
+import (
+	"os"
+	"path"
+	"sync"
+	"testing"
+
+	"github.com/konveyor/tackle2-hub/shared/addon/scm"
+	"github.com/konveyor/tackle2-hub/shared/api"
+	"github.com/konveyor/tackle2-hub/shared/binding"
+	"github.com/konveyor/tackle2-hub/shared/binding/client"
+	"github.com/konveyor/tackle2-hub/shared/nas"
+)
+
+// FakeHub in-memory hub.
+type FakeHub struct {
+	// Data task data.
+	Data any
+	// App the application.
+	App api.Application
+	// AddonDef the addon and extensions.
+	AddonDef api.Addon
+	// Identities (kind) associated with the application.
+	Identities  []api.Identity
+	Profiles    []api.AnalysisProfile
+	Targets     []api.Target
+	RuleSetList []api.RuleSet
+	Settings    map[string]any
+	Proxies     []api.Proxy
+	// Files id => path.
+	Files map[uint]string
+	// Downloaded files (count).
+	Downloaded int
+	// BucketDir task bucket.
+	BucketDir string
+	// Repositories URL => local directory.
+	Repositories map[string]string
+	// Updated repositories (count).
+	Updated int
+	// Previous analysis.
+	Previous *api.Analysis
+	//
+	// Posted files by name => content.
+	Posted map[string]string
+	// Uploaded manifests.
+	Uploaded []string
+	// Facts by source.
+	Facts      map[string]api.Map
+	Categories []api.TagCategory
+	Tags       []api.Tag
+	// AppTagIds by source.
+	AppTagIds map[string][]uint
+	nextId    uint
+	mutex     sync.Mutex
+}
+
+func (h *FakeHub) DataWith(object any) (err error) {
+	local := Local{}
+	err = local.assign(object, h.Data)
+	return
+}
+
+func (h *FakeHub) Application() (r *api.Application, err error) {
+	app := h.App
+	r = &app
+	return
+}
+
+func (h *FakeHub) Addon() (r *api.Addon, err error) {
+	addon := h.AddonDef
+	r = &addon
+	return
+}
+
+func (h *FakeHub) AppIdentity(appId uint, kind string) (r *api.Identity, found bool, err error) {
+	for i := range h.Identities {
+		if h.Identities[i].Kind == kind {
+			r = &h.Identities[i]
+			found = true
+			break
+		}
+	}
+	return
+}
+
+func (h *FakeHub) Identity(id uint) (r *api.Identity, err error) {
+	for i := range h.Identities {
+		if h.Identities[i].ID == id {
+			r = &h.Identities[i]
+			return
+		}
+	}
+	err = h.notFound()
+	return
+}
+
+func (h *FakeHub) Profile(id uint) (r *api.AnalysisProfile, err error) {
+	for i := range h.Profiles {
+		if h.Profiles[i].ID == id {
+			r = &h.Profiles[i]
+			return
+		}
+	}
+	err = h.notFound()
+	return
+}
+
+func (h *FakeHub) Target(id uint) (r *api.Target, err error) {
+	for i := range h.Targets {
+		if h.Targets[i].ID == id {
+			r = &h.Targets[i]
+			return
+		}
+	}
+	err = h.notFound()
+	return
+}
+
+func (h *FakeHub) RuleSet(id uint) (r *api.RuleSet, err error) {
+	for i := range h.RuleSetList {
+		if h.RuleSetList[i].ID == id {
+			r = &h.RuleSetList[i]
+			return
+		}
+	}
+	err = h.notFound()
+	return
+}
+
+func (h *FakeHub) RuleSets() (list []api.RuleSet, err error) {
+	list = h.RuleSetList
+	return
+}
+
+func (h *FakeHub) Setting(key string, v any) (err error) {
+	value, found := h.Settings[key]
+	if !found {
+		err = h.notFound()
+		return
+	}
+	local := Local{}
+	err = local.assign(v, value)
+	return
+}
+
+func (h *FakeHub) Proxy(kind string) (r *api.Proxy, err error) {
+	for i := range h.Proxies {
+		if h.Proxies[i].Kind == kind {
+			r = &h.Proxies[i]
+			break
+		}
+	}
+	return
+}
+
+func (h *FakeHub) Repository(destDir string, repository api.Repository, _ *api.Identity) (r scm.SCM, err error) {
+	source, found := h.Repositories[repository.URL]
+	if !found {
+		err = h.notFound()
+		return
+	}
+	r = &FakeRepository{Source: source, Path: destDir, hub: h}
+	return
+}
+
+func (h *FakeHub) FileGet(id uint, destination string) (err error) {
+	h.Downloaded++
+	p, found := h.Files[id]
+	if !found {
+		err = h.notFound()
+		return
+	}
+	local := Local{}
+	err = local.copy(p, destination)
+	return
+}
+
+func (h *FakeHub) FilePost(p string) (r *api.File, err error) {
+	b, err := os.ReadFile(p)
+	if err != nil {
+		return
+	}
+	if h.Posted == nil {
+		h.Posted = make(map[string]string)
+	}
+	r = &api.File{Name: path.Base(p), Path: p}
+	r.ID = h.newId()
+	h.Posted[r.Name] = string(b)
+	return
+}
+
+func (h *FakeHub) BucketGet(source, destination string) (err error) {
+	err = nas.CpDir(path.Join(h.BucketDir, source)+"/.", destination)
+	return
+}
+
+func (h *FakeHub) Analysis(appId uint) (r *api.Analysis, err error) {
+	if h.Previous == nil {
+		err = h.notFound()
+		return
+	}
+	r = h.Previous
+	return
+}
+
+func (h *FakeHub) AnalysisUpload(appId uint, manifest string) (r *api.Analysis, err error) {
+	b, err := os.ReadFile(manifest)
+	if err != nil {
+		return
+	}
+	h.Uploaded = append(h.Uploaded, string(b))
+	r = &api.Analysis{}
+	r.ID = h.newId()
+	return
+}
+
+func (h *FakeHub) FactReplace(appId uint, source string, facts api.Map) (err error) {
+	if h.Facts == nil {
+		h.Facts = make(map[string]api.Map)
+	}
+	h.Facts[source] = facts
+	return
+}
+
+func (h *FakeHub) AppFact(appId uint, key string) (v any, found bool, err error) {
+	fk := api.FactKey(key)
+	v, found = h.Facts[fk.Source()][fk.Name()]
+	return
+}
+
+func (h *FakeHub) TagCategory(name string) (r *api.TagCategory, found bool, err error) {
+	for i := range h.Categories {
+		if h.Categories[i].Name == name {
+			r = &h.Categories[i]
+			found = true
+			break
+		}
+	}
+	return
+}
+
+func (h *FakeHub) TagCategoryList() (list []api.TagCategory, err error) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	list = append(list, h.Categories...)
+	return
+}
+
+func (h *FakeHub) TagCategoryEnsure(r *api.TagCategory) (err error) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	for _, cat := range h.Categories {
+		if cat.Name == r.Name {
+			*r = cat
+			return
+		}
+	}
+	r.ID = h.newId()
+	h.Categories = append(h.Categories, *r)
+	return
+}
+
+func (h *FakeHub) TagCategoryUpdate(r *api.TagCategory) (err error) {
+	for i := range h.Categories {
+		if h.Categories[i].ID == r.ID {
+			h.Categories[i] = *r
+			return
+		}
+	}
+	err = &api.NotFound{}
+	return
+}
+
+func (h *FakeHub) TagList() (list []api.Tag, err error) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	list = append(list, h.Tags...)
+	return
+}
+
+func (h *FakeHub) TagEnsure(r *api.Tag) (err error) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	for _, tag := range h.Tags {
+		if tag.Name == r.Name && tag.Category.ID == r.Category.ID {
+			*r = tag
+			return
+		}
+	}
+	r.ID = h.newId()
+	h.Tags = append(h.Tags, *r)
+	return
+}
+
+func (h *FakeHub) AppTagList(appId uint) (list []api.TagRef, err error) {
+	for source, ids := range h.AppTagIds {
+		for _, id := range ids {
+			list = append(list, api.TagRef{ID: id, Source: source})
+		}
+	}
+	return
+}
+
+func (h *FakeHub) AppTagReplace(appId uint, source string, ids []uint) (err error) {
+	if h.AppTagIds == nil {
+		h.AppTagIds = make(map[string][]uint)
+	}
+	h.AppTagIds[source] = ids
+	return
+}
+
+// TagNames returns the tag (category=name) associated by source.
+func (h *FakeHub) TagNames(source string) (names []string) {
+	for _, id := range h.AppTagIds[source] {
+		for _, tag := range h.Tags {
+			if tag.ID != id {
+				continue
+			}
+			for _, cat := range h.Categories {
+				if cat.ID == tag.Category.ID {
+					names = append(names, cat.Name+"="+tag.Name)
+				}
+			}
+		}
+	}
+	return
+}
+
+func (h *FakeHub) newId() (id uint) {
+	h.nextId++
+	id = h.nextId
+	return
+}
+
+func (h *FakeHub) notFound() (err error) {
+	err = &api.NotFound{}
+	return
+}
+
+// FakeRepository repository fetched from a local directory.
+type FakeRepository struct {
+	Source string
+	Path   string
+	hub    *FakeHub
+}
+
+func (r *FakeRepository) Id() string                              { return r.Source }
+func (r *FakeRepository) Validate() (err error)                   { return }
+func (r *FakeRepository) Branch(ref string) (err error)           { return }
+func (r *FakeRepository) Commit(files []string, msg string) error { return nil }
+func (r *FakeRepository) Head() (commit string, err error)        { return "abc123", nil }
+func (r *FakeRepository) Clean() (err error)                      { return }
+func (r *FakeRepository) Update() (err error) {
+	r.hub.Updated++
+	err = os.RemoveAll(r.Path)
+	if err != nil {
+		return
+	}
+	err = r.Fetch()
+	return
+}
+
+func (r *FakeRepository) Fetch() (err error) {
+	err = nas.MkDir(r.Path, 0755)
+	if err != nil {
+		return
+	}
+	err = os.CopyFS(r.Path, os.DirFS(r.Source))
+	return
+}
+
+// useFakeHub installs the fake hub.
+// The task reports are accepted (and discarded).
+func useFakeHub(t *testing.T, fake *FakeHub) {
+	richClient := binding.New("")
+	richClient.Use(&client.Stub{
+		DoGet: func(path string, object any, params ...client.Param) (err error) {
+			switch r := object.(type) {
+			case *api.Task:
+				r.ID = 1
+				r.Application = &api.Ref{ID: fake.App.ID}
+			default:
+				err = &binding.NotFound{}
+			}
+			return
+		},
+		DoPost: func(path string, object any) (err error) {
+			return
+		},
+		DoPut: func(path string, object any, params ...client.Param) (err error) {
+			return
+		},
+		DoDelete: func(path string, params ...client.Param) (err error) {
+			return
+		},
+		DoFilePost: func(path, source string, object any) (err error) {
+			return
+		},
+		DoFilePostEncoded: func(path, source string, object any, encoding string) (err error) {
+			return
+		},
+		DoFilePatch: func(path string, buffer []byte) (err error) {
+			return
+		},
+	})
+	addon.Use(richClient)
+	addon.Load()
+	saved := hubApi
+	hubApi = fake
+	t.Cleanup(func() {
+		hubApi = saved
+	})
+}
+
+// useDirs sets the directories (and working directory) to the temp directory.
+func useDirs(t *testing.T) (tmp string) {
+	tmp = t.TempDir()
+	saved := []string{Dir, OptDir, SharedDir, CacheDir, SourceDir, RuleDir, BinDir, M2Dir, RuleCacheDir}
+	Dir = tmp
+	OptDir = path.Join(tmp, "opt")
+	SharedDir = path.Join(tmp, "shared")
+	CacheDir = path.Join(tmp, "cache")
+	SourceDir = path.Join(SharedDir, "source")
+	RuleDir = path.Join(tmp, "rules")
+	BinDir = path.Join(SharedDir, "bin")
+	M2Dir = path.Join(CacheDir, "m2")
+	RuleCacheDir = path.Join(CacheDir, "rules")
+	t.Chdir(tmp)
+	t.Cleanup(func() {
+		Dir = saved[0]
+		OptDir = saved[1]
+		SharedDir = saved[2]
+		CacheDir = saved[3]
+		SourceDir = saved[4]
+		RuleDir = saved[5]
+		BinDir = saved[6]
+		M2Dir = saved[7]
+		RuleCacheDir = saved[8]
+	})
+	return
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/konveyor/tackle2-addon-analyzer/builder"
+	hub "github.com/konveyor/tackle2-hub/shared/addon"
+	"github.com/konveyor/tackle2-hub/shared/addon/command"
+	"github.com/konveyor/tackle2-hub/shared/addon/scm"
+	"github.com/konveyor/tackle2-hub/shared/api"
+)
+
+// FactRules application fact (name) for the
+// rules fingerprint recorded with the analysis.
+const FactRules = "rules"
+
+// RulesFingerprint the rules fingerprint of an analysis.
+type RulesFingerprint struct {
+	// Analysis id.
+	Analysis uint `json:"analysis"`
+	// Digest of the resolved rules selection.
+	Digest string `json:"digest"`
+}
+
+// Incremental analysis.
+// Only the files changed since the commit recorded in the
+// previous analysis are analyzed. The incidents reported in
+// unchanged files are merged from the previous analysis.
+// The previous analysis is only used when the rules fingerprint
+// recorded with it matches the current rules selection.
+// When files have only been deleted, the analyzer is not run
+// and the previous analysis (less the deleted files) is reused.
+type Incremental struct {
+	// previous analysis.
+	previous *api.Analysis
+	// changed files (absolute paths).
+	changed map[string]bool
+	// included files (absolute paths) added or modified.
+	included []string
+}
+
+// Build determines the files changed since the previous analysis.
+// Falls back to full analysis when the changed files cannot be determined.
+func (r *Incremental) Build(appId uint, repository scm.SCM, location string) (err error) {
+	r.previous = nil
+	r.changed = make(map[string]bool)
+	r.included = nil
+	git, cast := repository.(*scm.Git)
+	if !cast {
+		addon.Activity("[INCREMENTAL] repository not git: using full analysis.")
+		return
+	}
+	previous, err := previousAnalysis(appId)
+	if err != nil {
+		return
+	}
+	if previous == nil || previous.Commit == "" {
+		addon.Activity("[INCREMENTAL] previous commit not found: using full analysis.")
+		return
+	}
+	paths, err := r.diff(git, previous.Commit)
+	if err != nil {
+		addon.Activity(
+			"[INCREMENTAL] diff (commit=%s) failed: using full analysis. %s",
+			previous.Commit,
+			err.Error())
+		err = nil
+		return
+	}
+	for _, p := range paths {
+		p = path.Join(git.Path, p)
+		rel, nErr := filepath.Rel(location, p)
+		if nErr != nil || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		r.changed[p] = true
+		_, nErr = os.Stat(p)
+		if nErr == nil {
+			r.included = append(r.included, p)
+		}
+	}
+	if len(r.changed) == 0 {
+		addon.Activity(
+			"[INCREMENTAL] no files changed since commit=%s: using full analysis.",
+			previous.Commit)
+		return
+	}
+	r.previous = previous
+	if len(r.included) == 0 {
+		addon.Activity(
+			"[INCREMENTAL] only files deleted since commit=%s: reusing analysis (id=%d).",
+			previous.Commit,
+			previous.ID)
+		return
+	}
+	addon.Activity(
+		"[INCREMENTAL] analyzing %d files changed since commit=%s.",
+		len(r.included),
+		previous.Commit)
+	return
+}
+
+// WithRules falls back to full analysis when the rules fingerprint
+// does not match the fingerprint recorded with the previous analysis.
+func (r *Incremental) WithRules(appId uint, digest string) (err error) {
+	if !r.Enabled() {
+		return
+	}
+	v, found, err := hubApi.AppFact(appId, Source+":"+FactRules)
+	if err != nil {
+		return
+	}
+	recorded := RulesFingerprint{}
+	if found {
+		b, _ := json.Marshal(v)
+		_ = json.Unmarshal(b, &recorded)
+	}
+	if digest != "" &&
+		recorded.Analysis == r.previous.ID &&
+		recorded.Digest == digest {
+		return
+	}
+	addon.Activity(
+		"[INCREMENTAL] rules changed since analysis (id=%d): using full analysis.",
+		r.previous.ID)
+	r.previous = nil
+	r.changed = make(map[string]bool)
+	r.included = nil
+	return
+}
+
+// Enabled returns true when incremental analysis is performed.
+func (r *Incremental) Enabled() (b bool) {
+	b = r.previous != nil
+	return
+}
+
+// Required returns true when the analyzer must be run.
+// Not required when incremental and only files were deleted.
+func (r *Incremental) Required() (b bool) {
+	b = !r.Enabled() || len(r.included) > 0
+	return
+}
+
+// Reuse returns the insights and dependencies reported by
+// the previous analysis when the analyzer is not required.
+// The insights are merged (less the changed files) by Merge().
+func (r *Incremental) Reuse() (insights *builder.Insights, deps *builder.Deps, err error) {
+	insights, err = builder.NewInsights(nil)
+	if err != nil {
+		return
+	}
+	deps = &builder.Deps{}
+	deps.Merge(r.previous.Dependencies)
+	return
+}
+
+// Included returns the files to be analyzed within the location.
+// Paths are relative to the location. Files outside the location
+// are not included.
+func (r *Incremental) Included(location string) (paths []string) {
+	for _, p := range r.included {
+		rel, err := filepath.Rel(location, p)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, "../") {
+			continue
+		}
+		paths = append(paths, rel)
+	}
+	return
+}
+
+// Affected returns true when files within the location are
+// to be analyzed. Always true when not enabled.
+func (r *Incremental) Affected(location string) (b bool) {
+	b = !r.Enabled() || len(r.Included(location)) > 0
+	return
+}
+
+// Changed returns true when the file has changed.
+func (r *Incremental) Changed(p string) (b bool) {
+	b = r.changed[p]
+	return
+}
+
+// Merge the unchanged incidents reported in the previous analysis.
+func (r *Incremental) Merge(insights *builder.Insights) {
+	if !r.Enabled() {
+		return
+	}
+	insights.Merge(r.previous.Insights, r.Changed)
+	addon.Activity(
+		"[INCREMENTAL] merged insights from analysis (id=%d).",
+		r.previous.ID)
+}
+
+// diff returns the files changed since the specified commit.
+// Paths are relative to the repository root.
+func (r *Incremental) diff(git *scm.Git, commit string) (paths []string, err error) {
+	cmd := command.New("/usr/bin/git")
+	cmd.Dir = git.Path
+	cmd.Env = append(
+		os.Environ(),
+		"GIT_TERMINAL_PROMPT=0",
+		"HOME="+git.Home)
+	cmd.Options.Add("diff", "--name-only", "--no-renames", commit)
+	err = cmd.Run()
+	if err != nil {
+		return
+	}
+	for _, p := range strings.Split(string(cmd.Output()), "\n") {
+		p = strings.TrimSpace(p)
+		if p != "" {
+			paths = append(paths, p)
+		}
+	}
+	return
+}
+
+// previousAnalysis returns the latest analysis for the application.
+// Returns nil when not found.
+func previousAnalysis(appId uint) (analysis *api.Analysis, err error) {
+	analysis, err = hubApi.Analysis(appId)
+	if err != nil {
+		if errors.Is(err, &hub.NotFound{}) {
+			analysis = nil
+			err = nil
+		}
+		return
+	}
+	if analysis.ID == 0 {
+		analysis = nil
+	}
+	return
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/konveyor/analyzer-lsp/provider"
+	"github.com/konveyor/tackle2-addon-analyzer/builder"
+	"github.com/konveyor/tackle2-hub/shared/addon/scm"
+	"github.com/konveyor/tackle2-hub/shared/api"
+	"github.com/onsi/gomega"
+)
+
+func TestIncrementalLocations(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	tmp := useDirs(t)
+	settingsFile := path.Join(tmp, "settings.xml")
+	fake := &FakeHub{
+		App: api.Application{
+			Resource: api.Resource{ID: 1},
+		},
+		AddonDef: api.Addon{
+			Extensions: []api.Extension{
+				{
+					Name: "java",
+					Metadata: api.Map{
+						"provider": api.Map{
+							"name": "java",
+							"initConfig": []api.Map{
+								{
+									"location": "$(builtin.location)",
+									"providerSpecificConfig": api.Map{
+										"mavenSettingsFile": "$(maven.settings)",
+									},
+								},
+							},
+						},
+						"resources": []api.Map{
+							{
+								"selector": "file:id=1",
+								"fields": []api.Map{
+									{"key": "maven.settings", "name": "path"},
+								},
+							},
+						},
+					},
+				},
+				{
+					Name: "builtin",
+					Metadata: api.Map{
+						"override": api.Map{
+							"path": "src",
+						},
+						"provider": api.Map{
+							"name": "builtin",
+							"initConfig": []api.Map{
+								{"location": "$(builtin.location)"},
+							},
+						},
+					},
+				},
+			},
+		},
+		Files: map[uint]string{1: settingsFile},
+	}
+	useFakeHub(t, fake)
+	err := os.WriteFile(settingsFile, []byte("<settings/>"), 0644)
+	g.Expect(err).To(gomega.BeNil())
+	mode := Mode{}
+	mode.path.appDir = "/app"
+	mode.path.modules = []string{"/app/a", "/app/b", "/app/c"}
+	mode.incremental.previous = &api.Analysis{Resource: api.Resource{ID: 1}}
+	mode.incremental.included = []string{
+		"/app/a/src/A.java",
+		"/app/b/B.java",
+	}
+	settings := Settings{}
+	err = settings.AppendExtensions(&mode)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(settings.Configs)).To(gomega.Equal(2))
+	// resources resolved once.
+	g.Expect(fake.Downloaded).To(gomega.Equal(1))
+	// included relative to each location.
+	java := settings.Configs[0].InitConfig
+	g.Expect(len(java)).To(gomega.Equal(2))
+	g.Expect(java[0].Location).To(gomega.Equal("/app/a"))
+	g.Expect(java[0].ProviderSpecificConfig[provider.IncludedPathsConfigKey]).To(
+		gomega.Equal([]any{"src/A.java"}))
+	g.Expect(java[1].Location).To(gomega.Equal("/app/b"))
+	g.Expect(java[1].ProviderSpecificConfig[provider.IncludedPathsConfigKey]).To(
+		gomega.Equal([]any{"B.java"}))
+	// relative to the override path.
+	builtin := settings.Configs[1].InitConfig
+	g.Expect(len(builtin)).To(gomega.Equal(1))
+	g.Expect(builtin[0].Location).To(gomega.Equal("/app/a/src"))
+	g.Expect(builtin[0].ProviderSpecificConfig[provider.IncludedPathsConfigKey]).To(
+		gomega.Equal([]any{"A.java"}))
+}
+
+func TestIncrementalDeleted(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	tmp := useDirs(t)
+	repo := path.Join(tmp, "repo")
+	err := os.MkdirAll(repo, 0755)
+	g.Expect(err).To(gomega.BeNil())
+	git := func(args ...string) (output string) {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repo
+		cmd.Env = append(
+			os.Environ(),
+			"GIT_AUTHOR_NAME=test",
+			"GIT_AUTHOR_EMAIL=test@test",
+			"GIT_COMMITTER_NAME=test",
+			"GIT_COMMITTER_EMAIL=test@test")
+		b, gErr := cmd.CombinedOutput()
+		g.Expect(gErr).To(gomega.BeNil(), string(b))
+		output = strings.TrimSpace(string(b))
+		return
+	}
+	a := path.Join(repo, "A.java")
+	b := path.Join(repo, "B.java")
+	git("init", "-q")
+	for _, p := range []string{a, b} {
+		err = os.WriteFile(p, []byte("class X {}\n"), 0644)
+		g.Expect(err).To(gomega.BeNil())
+	}
+	git("add", ".")
+	git("commit", "-q", "-m", "first")
+	commit := git("rev-parse", "HEAD")
+	git("rm", "-q", "B.java")
+	git("commit", "-q", "-m", "deleted")
+	fake := &FakeHub{
+		Previous: &api.Analysis{
+			Resource: api.Resource{ID: 4},
+			Commit:   commit,
+			Insights: []api.Insight{
+				{
+					RuleSet: "RULESET-A",
+					Rule:    "rule-001",
+					Labels:  []string{"tag=Language=Java"},
+					Incidents: []api.Incident{
+						{File: a, Message: "A"},
+					},
+				},
+				{
+					RuleSet: "RULESET-A",
+					Rule:    "rule-002",
+					Labels:  []string{"tag=Framework=Spring"},
+					Incidents: []api.Incident{
+						{File: b, Message: "B"},
+					},
+				},
+			},
+			Dependencies: []api.TechDependency{
+				{Provider: "java", Name: "spring"},
+			},
+		},
+	}
+	useFakeHub(t, fake)
+	mode := Mode{}
+	repository := &scm.Git{}
+	repository.Path = repo
+	repository.Home = tmp
+	err = mode.incremental.Build(1, repository, repo)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(mode.incremental.Enabled()).To(gomega.BeTrue())
+	g.Expect(mode.incremental.Required()).To(gomega.BeFalse())
+	// previous analysis reused (the analyzer is not run).
+	insights, deps, err := mode.Analyze(&Analyzer{})
+	g.Expect(err).To(gomega.BeNil())
+	mode.Merge(insights)
+	g.Expect(insights.Tags()).To(gomega.Equal([]string{"Language=Java"}))
+	manifest := path.Join(tmp, "manifest.yaml")
+	f, err := os.Create(manifest)
+	g.Expect(err).To(gomega.BeNil())
+	err = deps.Write(f)
+	_ = f.Close()
+	g.Expect(err).To(gomega.BeNil())
+	content, err := os.ReadFile(manifest)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(string(content)).To(gomega.ContainSubstring("name: spring"))
+}
+
+func TestIncrementalRules(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	rules := Rules{
+		Labels: Labels{
+			Included: []string{"konveyor.io/target=quarkus"},
+		},
+	}
+	rules.provenance.Repositories = []builder.RuleRepository{
+		{URL: "https://git.example.com/rules.git", Commit: "abc"},
+	}
+	rules.provenance.Files = []builder.RuleFile{{ID: 7}}
+	digest := rules.Fingerprint()
+	g.Expect(digest).ToNot(gomega.BeEmpty())
+	g.Expect(rules.Fingerprint()).To(gomega.Equal(digest))
+	// selection changed.
+	changed := rules
+	changed.Labels = Labels{Included: []string{"konveyor.io/target=eap8"}}
+	g.Expect(changed.Fingerprint()).ToNot(gomega.Equal(digest))
+	changed = rules
+	changed.provenance.Repositories = []builder.RuleRepository{
+		{URL: "https://git.example.com/rules.git", Commit: "def"},
+	}
+	g.Expect(changed.Fingerprint()).ToNot(gomega.Equal(digest))
+	changed = rules
+	changed.provenance.Files = []builder.RuleFile{{ID: 8}}
+	g.Expect(changed.Fingerprint()).ToNot(gomega.Equal(digest))
+	// bucket not versioned.
+	changed = rules
+	changed.provenance.Bucket = "/rules"
+	g.Expect(changed.Fingerprint()).To(gomega.BeEmpty())
+	// matched.
+	fake := &FakeHub{
+		Facts: map[string]api.Map{
+			Source: {
+				FactRules: RulesFingerprint{Analysis: 4, Digest: digest},
+			},
+		},
+	}
+	useFakeHub(t, fake)
+	mode := Mode{}
+	mode.incremental.previous = &api.Analysis{Resource: api.Resource{ID: 4}}
+	mode.incremental.included = []string{"/app/A.java"}
+	err := mode.WithRules(1, &rules)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(mode.incremental.Enabled()).To(gomega.BeTrue())
+	// recorded with another analysis.
+	mode.incremental.previous = &api.Analysis{Resource: api.Resource{ID: 5}}
+	err = mode.WithRules(1, &rules)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(mode.incremental.Enabled()).To(gomega.BeFalse())
+	g.Expect(mode.incremental.Required()).To(gomega.BeTrue())
+	// rules changed.
+	mode.incremental.previous = &api.Analysis{Resource: api.Resource{ID: 4}}
+	changed = rules
+	changed.Labels = Labels{Included: []string{"konveyor.io/target=eap8"}}
+	err = mode.WithRules(1, &changed)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(mode.incremental.Enabled()).To(gomega.BeFalse())
+	// not recorded.
+	fake.Facts = nil
+	mode.incremental.previous = &api.Analysis{Resource: api.Resource{ID: 4}}
+	err = mode.WithRules(1, &rules)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(mode.incremental.Enabled()).To(gomega.BeFalse())
+}
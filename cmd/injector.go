@@ -7,6 +7,7 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -165,10 +166,41 @@ type Resource struct {
 	Fields   []Field `json:"fields"`
 }
 
+// Override provider overrides.
+// Example:
+//
+//	metadata:
+//	 override:
+//	   mode: source-only
+//	   path: src/main
+type Override struct {
+	// Mode the analysis mode (full|source-only).
+	Mode provider.AnalysisMode `json:"mode,omitempty"`
+	// Path the sub-path (relative to the location) to be analyzed.
+	Path string `json:"path,omitempty"`
+}
+
+// Validate the overrides.
+func (r *Override) Validate() (err error) {
+	switch r.Mode {
+	case "",
+		provider.FullAnalysisMode,
+		provider.SourceOnlyAnalysisMode:
+	default:
+		err = fmt.Errorf(
+			"Provider override: mode=%s not valid. expected: %s|%s",
+			r.Mode,
+			provider.FullAnalysisMode,
+			provider.SourceOnlyAnalysisMode)
+	}
+	return
+}
+
 // Metadata for provider extensions.
 type Metadata struct {
 	Resources []Resource      `json:"resources,omitempty"`
 	Provider  provider.Config `json:"provider"`
+	Override  Override        `json:"override"`
 }
 
 // ParsedSelector -
@@ -199,9 +231,12 @@ func (p *ParsedSelector) With(s string) {
 }
 
 // Injector replaces variables in the object.
-// format: $(variable).
+// format: $(expression). See: Expression.
 type Injector struct {
 	dict map[string]any
+	err  error
+	// unresolved keys.
+	unresolved []string
 }
 
 // Inject resources into extension metadata.
@@ -209,6 +244,10 @@ func (r *Injector) Inject(md *Metadata) (err error) {
 	r.init()
 	mp := r.asMap(md)
 	mp = r.inject(mp).(map[string]any)
+	if r.err != nil {
+		err = r.err
+		return
+	}
 	err = r.object(mp, md)
 	if err != nil {
 		return
@@ -253,7 +292,16 @@ func (r *Injector) inject(in any) (out any) {
 			if len(match) < 3 {
 				break
 			}
-			v := r.dict[match[2]]
+			expression := Expression{dict: r.dict}
+			v, err := expression.Eval(match[2])
+			r.unresolved = append(r.unresolved, expression.missing...)
+			if err != nil {
+				if r.err == nil {
+					r.err = err
+				}
+				out = node
+				return
+			}
 			if len(node) > len(match[0]) {
 				node = strings.Replace(
 					node,
@@ -296,6 +344,16 @@ func (r *Injector) string(object any) (s string) {
 }
 
 // ResourceInjector inject resources into extension metadata.
+// Selectors:
+//   - identity:kind=<kind> the application identity.
+//   - setting:key=<key> the setting.
+//   - application: the application (name, repository, binary, ...).
+//   - application:fact=<source:name> the application fact.
+//   - tag:category=<name> the application tags in the category.
+//   - proxy:kind=<kind> the (resolved) proxy url.
+//   - file:id=<id> the hub file downloaded to the field path.
+//     Files are selected by id; names are not unique in the hub.
+//
 // Example:
 //
 //	metadata:
@@ -316,42 +374,68 @@ func (r *Injector) string(object any) (s string) {
 //	   fields:
 //	   - key: maven.insecure
 //	     name: value
+//
+// The resources are resolved (and files downloaded) once and
+// reused when the injector is used for each location.
 type ResourceInjector struct {
 	Injector
+	// resources resolved.
+	resources map[string]any
 }
 
 // Inject resources into extension metadata.
+// The resources are resolved on first use and added to the
+// dictionary set by Use().
 func (r *ResourceInjector) Inject(md *Metadata) (err error) {
-	r.init()
-	err = r.build(md)
-	if err != nil {
-		return
+	if r.resources == nil {
+		used := r.dict
+		r.dict = make(map[string]any)
+		err = r.build(md)
+		if err != nil {
+			return
+		}
+		r.resources = r.dict
+		r.dict = used
+	}
+	dict := make(map[string]any)
+	for k, v := range r.dict {
+		dict[k] = v
+	}
+	for k, v := range r.resources {
+		if _, found := dict[k]; found {
+			err = &KeyConflictError{
+				Key:   k,
+				Value: v,
+			}
+			return
+		}
+		dict[k] = v
 	}
+	r.dict = dict
+	r.err = nil
+	r.unresolved = nil
 	err = r.Injector.Inject(md)
 	return
 }
 
 // build builds resource dictionary.
 func (r *ResourceInjector) build(md *Metadata) (err error) {
-	application, err := addon.Task.Application()
+	application, err := hubApi.Application()
 	if err != nil {
 		return
 	}
 	for _, resource := range md.Resources {
 		err = r.addDefaults(&resource)
+		if err != nil {
+			return
+		}
 	}
 	for _, resource := range md.Resources {
 		parsed := ParsedSelector{}
 		parsed.With(resource.Selector)
 		switch strings.ToLower(parsed.kind) {
 		case "identity":
-			identity, found, nErr :=
-				addon.Application.Select(application.ID).Identity.
-					Decrypted().
-					Search().
-					Direct(parsed.value).
-					Indirect(parsed.value).
-					Find()
+			identity, found, nErr := hubApi.AppIdentity(application.ID, parsed.value)
 			if nErr != nil {
 				err = nErr
 				return
@@ -364,7 +448,7 @@ func (r *ResourceInjector) build(md *Metadata) (err error) {
 			}
 		case "setting":
 			setting := &api.Setting{}
-			err = addon.Setting.Get(parsed.value, &setting.Value)
+			err = hubApi.Setting(parsed.value, &setting.Value)
 			if err != nil {
 				return
 			}
@@ -372,6 +456,56 @@ func (r *ResourceInjector) build(md *Metadata) (err error) {
 			if err != nil {
 				return
 			}
+		case "application":
+			var object map[string]any
+			object, err = r.application(application, &parsed)
+			if err != nil {
+				return
+			}
+			if object != nil {
+				err = r.add(&resource, object)
+				if err != nil {
+					return
+				}
+			}
+		case "tag":
+			var object map[string]any
+			object, err = r.tags(application, parsed.value)
+			if err != nil {
+				return
+			}
+			err = r.add(&resource, object)
+			if err != nil {
+				return
+			}
+		case "proxy":
+			settings := Settings{}
+			url, excluded, nErr := settings.getProxy(parsed.value)
+			if nErr != nil {
+				err = nErr
+				return
+			}
+			if url != "" {
+				object := map[string]any{
+					"kind":     parsed.value,
+					"url":      url,
+					"excluded": strings.Join(excluded, ","),
+				}
+				err = r.add(&resource, object)
+				if err != nil {
+					return
+				}
+			}
+		case "file":
+			id, nErr := strconv.ParseUint(parsed.value, 10, 0)
+			if parsed.name != "id" || nErr != nil {
+				err = &SelectorNotSupported{Selector: resource.Selector}
+				return
+			}
+			err = r.addFile(&resource, uint(id))
+			if err != nil {
+				return
+			}
 		default:
 			err = &SelectorNotSupported{Selector: resource.Selector}
 			return
@@ -380,6 +514,111 @@ func (r *ResourceInjector) build(md *Metadata) (err error) {
 	return
 }
 
+// application returns the application resource object.
+// Selectors:
+//   - application: the application attributes.
+//   - application:fact=<source:name> the application fact.
+//
+// Returns nil when the fact is not found.
+func (r *ResourceInjector) application(application *api.Application, parsed *ParsedSelector) (object map[string]any, err error) {
+	switch parsed.name {
+	case "":
+		object = map[string]any{
+			"id":          application.ID,
+			"name":        application.Name,
+			"description": application.Description,
+			"binary":      application.Binary,
+		}
+		if application.Repository != nil {
+			object["repository"] = application.Repository.URL
+			object["branch"] = application.Repository.Branch
+			object["path"] = application.Repository.Path
+		}
+	case "fact":
+		v, found, nErr := hubApi.AppFact(application.ID, parsed.value)
+		if nErr != nil {
+			err = nErr
+			return
+		}
+		if found {
+			object = map[string]any{
+				"key":   parsed.value,
+				"value": v,
+			}
+		}
+	default:
+		err = &SelectorNotSupported{Selector: "application:" + parsed.name}
+	}
+	return
+}
+
+// tags returns the tags resource object for the application
+// tags in the category. The names are sorted.
+func (r *ResourceInjector) tags(application *api.Application, category string) (object map[string]any, err error) {
+	names := []string{}
+	cat, found, err := hubApi.TagCategory(category)
+	if err != nil {
+		return
+	}
+	if found {
+		inCategory := make(map[uint]bool)
+		for _, ref := range cat.Tags {
+			inCategory[ref.ID] = true
+		}
+		for _, ref := range application.Tags {
+			if inCategory[ref.ID] {
+				inCategory[ref.ID] = false
+				names = append(names, ref.Name)
+			}
+		}
+	}
+	sort.Strings(names)
+	object = map[string]any{
+		"category": category,
+		"names":    strings.Join(names, ","),
+		"count":    len(names),
+	}
+	return
+}
+
+// addFile downloads the file for each field and adds the path.
+// The file is downloaded to the field path when specified.
+func (r *ResourceInjector) addFile(resource *Resource, id uint) (err error) {
+	for _, f := range resource.Fields {
+		destination := f.Path
+		if destination == "" {
+			destination = filepath.Join(OptDir, "files", strconv.Itoa(int(id)))
+		}
+		object := map[string]any{
+			"id":   id,
+			"path": destination,
+		}
+		v, found := object[f.Name]
+		if !found {
+			err = &FieldNotMatched{
+				Kind:  resource.Selector,
+				Field: f.Name,
+			}
+			return
+		}
+		err = nas.MkDir(filepath.Dir(destination), 0755)
+		if err != nil {
+			return
+		}
+		err = hubApi.FileGet(id, destination)
+		if err != nil {
+			return
+		}
+		field := f
+		field.Path = ""
+		err = r.addField(&field, v)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
 // addDefaults adds defaults when specified.
 func (r *ResourceInjector) addDefaults(resource *Resource) (err error) {
 	for _, f := range resource.Fields {
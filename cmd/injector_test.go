@@ -0,0 +1,120 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/konveyor/analyzer-lsp/provider"
+	"github.com/konveyor/tackle2-hub/shared/api"
+	"github.com/onsi/gomega"
+)
+
+func TestResourceInjectorSelectors(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	tmp := useDirs(t)
+	npmrc := path.Join(tmp, ".npmrc")
+	err := os.WriteFile(npmrc, []byte("registry=https://npm.example.com\n"), 0644)
+	g.Expect(err).To(gomega.BeNil())
+	fake := &FakeHub{
+		App: api.Application{
+			Resource: api.Resource{ID: 1},
+			Name:     "demo",
+			Binary:   "mvn://org.demo:demo:1.0",
+			Repository: &api.Repository{
+				URL: "https://git.example.com/demo.git",
+			},
+			Tags: []api.TagRef{
+				{ID: 2, Name: "Java"},
+				{ID: 3, Name: "Go"},
+				{ID: 4, Name: "Linux"},
+			},
+		},
+		Categories: []api.TagCategory{
+			{
+				Resource: api.Resource{ID: 1},
+				Name:     "Language",
+				Tags:     []api.Ref{{ID: 2}, {ID: 3}},
+			},
+		},
+		Facts: map[string]api.Map{
+			"discovery": {"build": "gradle"},
+		},
+		Proxies: []api.Proxy{
+			{Kind: "http", Host: "proxy.example.com", Port: 3128},
+		},
+		Files: map[uint]string{1: npmrc},
+	}
+	useFakeHub(t, fake)
+	md := &Metadata{
+		Provider: provider.Config{
+			InitConfig: []provider.InitConfig{
+				{
+					ProviderSpecificConfig: map[string]any{
+						"name":      "$(app.name)",
+						"url":       "$(app.url)",
+						"binary":    "$(app.binary)",
+						"build":     "$(app.build)",
+						"languages": "$(app.languages)",
+						"proxy":     "$(proxy.url)",
+						"npmrc":     "$(npmrc.path)",
+					},
+				},
+			},
+		},
+		Resources: []Resource{
+			{
+				Selector: "application:",
+				Fields: []Field{
+					{Name: "name", Key: "app.name"},
+					{Name: "repository", Key: "app.url"},
+					{Name: "binary", Key: "app.binary"},
+				},
+			},
+			{
+				Selector: "application:fact=discovery:build",
+				Fields: []Field{
+					{Name: "value", Key: "app.build"},
+				},
+			},
+			{
+				Selector: "tag:category=Language",
+				Fields: []Field{
+					{Name: "names", Key: "app.languages"},
+				},
+			},
+			{
+				Selector: "proxy:kind=http",
+				Fields: []Field{
+					{Name: "url", Key: "proxy.url"},
+				},
+			},
+			{
+				Selector: "file:id=1",
+				Fields: []Field{
+					{Name: "path", Key: "npmrc.path", Path: path.Join(tmp, "opt", "npmrc")},
+				},
+			},
+		},
+	}
+	inj := ResourceInjector{}
+	err = inj.Inject(md)
+	g.Expect(err).To(gomega.BeNil())
+	d := md.Provider.InitConfig[0].ProviderSpecificConfig
+	g.Expect(d["name"]).To(gomega.Equal("demo"))
+	g.Expect(d["url"]).To(gomega.Equal("https://git.example.com/demo.git"))
+	g.Expect(d["binary"]).To(gomega.Equal("mvn://org.demo:demo:1.0"))
+	g.Expect(d["build"]).To(gomega.Equal("gradle"))
+	g.Expect(d["languages"]).To(gomega.Equal("Go,Java"))
+	g.Expect(d["proxy"]).To(gomega.Equal("http://proxy.example.com:3128"))
+	g.Expect(d["npmrc"]).To(gomega.Equal(path.Join(tmp, "opt", "npmrc")))
+	b, err := os.ReadFile(path.Join(tmp, "opt", "npmrc"))
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(string(b)).To(gomega.ContainSubstring("npm.example.com"))
+	// not supported.
+	md.Resources = []Resource{{Selector: "application:owner"}}
+	inj = ResourceInjector{}
+	err = inj.Inject(md)
+	g.Expect(errors.Is(err, &SelectorNotSupported{})).To(gomega.BeTrue())
+}
@@ -0,0 +1,935 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/konveyor/analyzer-lsp/parser"
+	"github.com/konveyor/tackle2-hub/shared/addon/adapter"
+	"github.com/konveyor/tackle2-hub/shared/addon/scm"
+	"github.com/konveyor/tackle2-hub/shared/api"
+	"github.com/konveyor/tackle2-hub/shared/binding"
+	"github.com/konveyor/tackle2-hub/shared/binding/client"
+	"github.com/konveyor/tackle2-hub/shared/nas"
+	"gopkg.in/yaml.v3"
+)
+
+// UseLocal installs the local hub (standalone mode).
+// The command line arguments are parsed and the inputs loaded.
+// Exits when the arguments or inputs are not valid.
+func UseLocal(args []string) {
+	local := &Local{}
+	err := local.Parse(args)
+	if err == nil {
+		err = local.Load()
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(2)
+	}
+	local.Use()
+}
+
+// LocalKind the (application) repository kind for the local source.
+const LocalKind = "local"
+
+// Local standalone mode.
+// The hub is emulated using local files so that the addon
+// may be run (and debugged) without deploying a task.
+// Inputs:
+//   - data: the task data (Data) document.
+//   - application: the application descriptor.
+//   - addon: the addon and extension (CR) descriptors.
+//   - rules: directory of rulesets. Each sub-directory is a ruleset
+//     containing an (optional) ruleset.yaml and rule files.
+//   - source: the source directory to be analyzed. When specified, the
+//     source is fetched (copied) as the application repository.
+//   - bucket: the task bucket directory.
+//   - settings: hub settings (key: value) document.
+//
+// Outputs (written to the output directory):
+//   - report.yaml: the task report.
+//   - manifest.yaml: the analysis manifest.
+//   - facts.yaml: the application facts.
+//   - tags.yaml: the application tags.
+//   - tag-evidence.yaml: the tag evidence (attached).
+//   - attached (and uploaded) files.
+type Local struct {
+	DataPath        string
+	ApplicationPath string
+	AddonPath       string
+	RuleSetDir      string
+	Source          string
+	BucketDir       string
+	SettingsPath    string
+	Output          string
+	Extensions      []string
+	//
+	task        api.Task
+	application api.Application
+	addon       api.Addon
+	ruleSets    []api.RuleSet
+	settings    map[string]any
+	files       map[uint]string
+	categories  []api.TagCategory
+	tags        []api.Tag
+	appTags     map[string][]api.TagRef
+	facts       map[string]api.Map
+	nextId      uint
+	mutex       sync.Mutex
+}
+
+// Parse command line arguments.
+func (r *Local) Parse(args []string) (err error) {
+	var extensions string
+	flags := flag.NewFlagSet("local", flag.ContinueOnError)
+	flags.StringVar(&r.DataPath, "data", "", "task data document.")
+	flags.StringVar(&r.ApplicationPath, "application", "", "application descriptor.")
+	flags.StringVar(&r.AddonPath, "addon", "", "addon and extension (CR) descriptors.")
+	flags.StringVar(&extensions, "extensions", "", "extensions (comma separated). default: all.")
+	flags.StringVar(&r.RuleSetDir, "rules", "", "directory of rulesets.")
+	flags.StringVar(&r.Source, "source", "", "source directory to be analyzed.")
+	flags.StringVar(&r.BucketDir, "bucket", "", "task bucket directory.")
+	flags.StringVar(&r.SettingsPath, "settings", "", "hub settings document.")
+	flags.StringVar(&r.Output, "output", path.Join(Dir, "output"), "output directory.")
+	err = flags.Parse(args)
+	if err != nil {
+		return
+	}
+	if r.ApplicationPath == "" {
+		err = errors.New("-application required.")
+		return
+	}
+	for _, name := range strings.Split(extensions, ",") {
+		name = strings.TrimSpace(name)
+		if name != "" {
+			r.Extensions = append(r.Extensions, name)
+		}
+	}
+	if r.Source != "" {
+		r.Source, err = filepath.Abs(r.Source)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+// Load the local files.
+func (r *Local) Load() (err error) {
+	r.nextId = 1
+	r.files = make(map[uint]string)
+	r.appTags = make(map[string][]api.TagRef)
+	r.facts = make(map[string]api.Map)
+	err = nas.MkDir(r.Output, 0755)
+	if err != nil {
+		return
+	}
+	err = r.readYaml(r.ApplicationPath, &r.application)
+	if err != nil {
+		return
+	}
+	if r.application.ID == 0 {
+		r.application.ID = 1
+	}
+	if r.Source != "" {
+		r.application.Repository = &api.Repository{
+			Kind: LocalKind,
+			URL:  r.Source,
+		}
+	}
+	err = r.loadAddon()
+	if err != nil {
+		return
+	}
+	err = r.loadRuleSets()
+	if err != nil {
+		return
+	}
+	err = r.loadSettings()
+	if err != nil {
+		return
+	}
+	r.task = api.Task{
+		Name:        "local",
+		Addon:       r.addon.Name,
+		Extensions:  r.Extensions,
+		Application: &api.Ref{ID: r.application.ID, Name: r.application.Name},
+	}
+	r.task.ID = 1
+	if r.DataPath != "" {
+		err = r.readYaml(r.DataPath, &r.task.Data)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+// Use installs the local hub.
+// The addon adapter (task reporting) uses the local client.
+func (r *Local) Use() {
+	richClient := &binding.RichClient{}
+	richClient.Use(r.client())
+	addon.Use(richClient)
+	hubApi = r
+}
+
+// loadAddon loads the addon and extensions.
+// The descriptors are the (kubernetes) Addon and Extension CRs.
+func (r *Local) loadAddon() (err error) {
+	r.addon = api.Addon{Name: "analyzer"}
+	if r.AddonPath == "" {
+		return
+	}
+	f, err := os.Open(r.AddonPath)
+	if err != nil {
+		return
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	type CR struct {
+		Kind     string `json:"kind"`
+		Metadata struct {
+			Name string `json:"name"`
+		} `json:"metadata"`
+		Spec struct {
+			Addon     string `json:"addon"`
+			Container any    `json:"container"`
+			Metadata  any    `json:"metadata"`
+		} `json:"spec"`
+	}
+	var all []string
+	decoder := yaml.NewDecoder(f)
+	for {
+		var doc any
+		err = decoder.Decode(&doc)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				err = nil
+				break
+			}
+			return
+		}
+		cr := CR{}
+		err = r.assign(&cr, doc)
+		if err != nil {
+			return
+		}
+		switch cr.Kind {
+		case "Addon":
+			r.addon.Name = cr.Metadata.Name
+		case "Extension":
+			extension := api.Extension{
+				Name:     cr.Metadata.Name,
+				Addon:    cr.Spec.Addon,
+				Metadata: cr.Spec.Metadata,
+			}
+			err = r.assign(&extension.Container, cr.Spec.Container)
+			if err != nil {
+				return
+			}
+			r.addon.Extensions = append(r.addon.Extensions, extension)
+			all = append(all, extension.Name)
+		}
+	}
+	if len(r.Extensions) == 0 {
+		r.Extensions = all
+	}
+	return
+}
+
+// loadRuleSets loads the rulesets.
+// Each sub-directory is a ruleset. The ruleset labels
+// are read from the ruleset.yaml and applied to each rule.
+func (r *Local) loadRuleSets() (err error) {
+	if r.RuleSetDir == "" {
+		return
+	}
+	entries, err := os.ReadDir(r.RuleSetDir)
+	if err != nil {
+		return
+	}
+	for _, ent := range entries {
+		if !ent.IsDir() {
+			continue
+		}
+		dir := path.Join(r.RuleSetDir, ent.Name())
+		md := struct {
+			Name        string   `json:"name"`
+			Description string   `json:"description"`
+			Labels      []string `json:"labels"`
+		}{}
+		mdPath := path.Join(dir, parser.RULE_SET_GOLDEN_FILE_NAME)
+		_, nErr := os.Stat(mdPath)
+		if nErr == nil {
+			err = r.readYaml(mdPath, &md)
+			if err != nil {
+				return
+			}
+		}
+		if md.Name == "" {
+			md.Name = ent.Name()
+		}
+		ruleSet := api.RuleSet{
+			Name:        md.Name,
+			Description: md.Description,
+		}
+		ruleSet.ID = r.newId()
+		var files []os.DirEntry
+		files, err = os.ReadDir(dir)
+		if err != nil {
+			return
+		}
+		for _, file := range files {
+			name := file.Name()
+			if file.IsDir() || name == parser.RULE_SET_GOLDEN_FILE_NAME {
+				continue
+			}
+			id := r.newId()
+			r.files[id] = path.Join(dir, name)
+			ruleSet.Rules = append(
+				ruleSet.Rules,
+				api.Rule{
+					Name:   name,
+					Labels: md.Labels,
+					File:   &api.Ref{ID: id, Name: name},
+				})
+		}
+		r.ruleSets = append(r.ruleSets, ruleSet)
+	}
+	return
+}
+
+// loadSettings loads the hub settings.
+func (r *Local) loadSettings() (err error) {
+	r.settings = map[string]any{
+		"git.insecure.enabled": false,
+		"svn.insecure.enabled": false,
+		"mvn.insecure.enabled": false,
+	}
+	if r.SettingsPath == "" {
+		return
+	}
+	settings := make(map[string]any)
+	err = r.readYaml(r.SettingsPath, &settings)
+	if err != nil {
+		return
+	}
+	for k, v := range settings {
+		r.settings[k] = v
+	}
+	return
+}
+
+// DataWith populates the task data object.
+func (r *Local) DataWith(object any) (err error) {
+	err = r.assign(object, r.task.Data)
+	return
+}
+
+// Application returns the application.
+func (r *Local) Application() (application *api.Application, err error) {
+	application = &api.Application{}
+	err = r.assign(application, r.application)
+	return
+}
+
+// Addon returns the addon and selected extensions.
+// The extensions are injected.
+func (r *Local) Addon() (definition *api.Addon, err error) {
+	definition = &api.Addon{}
+	err = r.assign(definition, r.addon)
+	if err != nil {
+		return
+	}
+	selected := make(map[string]bool)
+	for _, name := range r.Extensions {
+		selected[name] = true
+	}
+	var extensions []api.Extension
+	for _, extension := range definition.Extensions {
+		if !selected[extension.Name] {
+			continue
+		}
+		injector := adapter.EnvInjector{}
+		injector.Inject(&extension)
+		extensions = append(extensions, extension)
+	}
+	definition.Extensions = extensions
+	return
+}
+
+// AppIdentity identities are not supported.
+func (r *Local) AppIdentity(appId uint, kind string) (identity *api.Identity, found bool, err error) {
+	return
+}
+
+// Identity identities are not supported.
+func (r *Local) Identity(id uint) (identity *api.Identity, err error) {
+	err = r.notFound("identity")
+	return
+}
+
+// Profile profiles are not supported.
+func (r *Local) Profile(id uint) (profile *api.AnalysisProfile, err error) {
+	err = r.notFound("profile")
+	return
+}
+
+// Target targets are not supported.
+func (r *Local) Target(id uint) (target *api.Target, err error) {
+	err = r.notFound("target")
+	return
+}
+
+// RuleSet returns the ruleset.
+func (r *Local) RuleSet(id uint) (ruleSet *api.RuleSet, err error) {
+	for i := range r.ruleSets {
+		if r.ruleSets[i].ID == id {
+			ruleSet = &api.RuleSet{}
+			err = r.assign(ruleSet, r.ruleSets[i])
+			return
+		}
+	}
+	err = r.notFound("ruleset")
+	return
+}
+
+// RuleSets returns all rulesets.
+func (r *Local) RuleSets() (list []api.RuleSet, err error) {
+	err = r.assign(&list, r.ruleSets)
+	return
+}
+
+// Setting populates the setting value.
+func (r *Local) Setting(key string, v any) (err error) {
+	value, found := r.settings[key]
+	if !found {
+		err = r.notFound(key)
+		return
+	}
+	err = r.assign(v, value)
+	return
+}
+
+// Proxy proxies are not supported.
+func (r *Local) Proxy(kind string) (proxy *api.Proxy, err error) {
+	return
+}
+
+// Repository returns the SCM repository.
+// The local source is fetched (copied) when specified.
+// Otherwise, the repository is built as by scm.New() using the
+// local settings. Proxies are not supported.
+func (r *Local) Repository(destDir string, repository api.Repository, identity *api.Identity) (repo scm.SCM, err error) {
+	remote := scm.Remote{
+		Kind:   repository.Kind,
+		URL:    repository.URL,
+		Branch: repository.Branch,
+		Path:   repository.Path,
+	}
+	if identity != nil {
+		remote.Identity = &scm.Identity{
+			ID:       identity.ID,
+			Name:     identity.Name,
+			User:     identity.User,
+			Password: identity.Password,
+			Key:      identity.Key,
+		}
+	}
+	switch remote.Kind {
+	case LocalKind:
+		repo = &LocalRepository{Source: repository.URL, Path: destDir}
+		return
+	case "subversion":
+		err = r.Setting("svn.insecure.enabled", &remote.Insecure)
+		if err != nil {
+			return
+		}
+		svn := &scm.Subversion{}
+		svn.Remote = remote
+		svn.Path = destDir
+		svn.Home = filepath.Join(Dir, ".svn", svn.Id())
+		svn.Proxies = scm.ProxyMap{}
+		repo = svn
+	default:
+		err = r.Setting("git.insecure.enabled", &remote.Insecure)
+		if err != nil {
+			return
+		}
+		git := &scm.Git{}
+		git.Remote = remote
+		git.Path = destDir
+		git.Home = filepath.Join(Dir, ".git", git.Id())
+		git.Proxies = scm.ProxyMap{}
+		repo = git
+	}
+	err = repo.Validate()
+	return
+}
+
+// FileGet copies a file.
+func (r *Local) FileGet(id uint, destination string) (err error) {
+	r.mutex.Lock()
+	file, found := r.files[id]
+	r.mutex.Unlock()
+	if !found {
+		err = r.notFound("file")
+		return
+	}
+	err = r.copy(file, destination)
+	return
+}
+
+// FilePost copies the file to the output directory.
+func (r *Local) FilePost(p string) (f *api.File, err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	f, err = r.filePost(path.Base(p), p)
+	return
+}
+
+// BucketGet copies the bucket content.
+func (r *Local) BucketGet(source, destination string) (err error) {
+	if r.BucketDir == "" {
+		err = r.notFound(source)
+		return
+	}
+	source = path.Join(r.BucketDir, source)
+	st, err := os.Stat(source)
+	if err != nil {
+		return
+	}
+	if st.IsDir() {
+		err = nas.CpDir(source+"/.", destination)
+		return
+	}
+	err = r.copy(source, destination)
+	return
+}
+
+// Analysis there is no previous analysis.
+func (r *Local) Analysis(appId uint) (analysis *api.Analysis, err error) {
+	err = r.notFound("analysis")
+	return
+}
+
+// AnalysisUpload copies the manifest to the output directory.
+func (r *Local) AnalysisUpload(appId uint, manifest string) (analysis *api.Analysis, err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	err = r.copy(manifest, path.Join(r.Output, path.Base(manifest)))
+	if err != nil {
+		return
+	}
+	analysis = &api.Analysis{}
+	analysis.ID = r.newId()
+	return
+}
+
+// FactReplace replaces the application facts by source.
+func (r *Local) FactReplace(appId uint, source string, facts api.Map) (err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.facts[source] = facts
+	err = r.writeYaml("facts.yaml", r.facts)
+	return
+}
+
+// AppFact finds an application fact by (source:name) key.
+func (r *Local) AppFact(appId uint, key string) (v any, found bool, err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	fk := api.FactKey(key)
+	v, found = r.facts[fk.Source()][fk.Name()]
+	return
+}
+
+// TagCategory finds the tag category by name.
+func (r *Local) TagCategory(name string) (cat *api.TagCategory, found bool, err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for i := range r.categories {
+		if r.categories[i].Name == name {
+			found = true
+			cat = &api.TagCategory{}
+			*cat = r.categories[i]
+			break
+		}
+	}
+	return
+}
+
+// TagCategoryList returns all tag categories.
+func (r *Local) TagCategoryList() (list []api.TagCategory, err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	list = append(list, r.categories...)
+	return
+}
+
+// TagCategoryEnsure ensures the tag category exists.
+func (r *Local) TagCategoryEnsure(cat *api.TagCategory) (err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for i := range r.categories {
+		if r.categories[i].Name == cat.Name {
+			*cat = r.categories[i]
+			return
+		}
+	}
+	cat.ID = r.newId()
+	r.categories = append(r.categories, *cat)
+	return
+}
+
+// TagCategoryUpdate updates the tag category.
+func (r *Local) TagCategoryUpdate(cat *api.TagCategory) (err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for i := range r.categories {
+		if r.categories[i].ID == cat.ID {
+			r.categories[i] = *cat
+			return
+		}
+	}
+	err = r.notFound("tag category")
+	return
+}
+
+// TagList returns all tags.
+func (r *Local) TagList() (list []api.Tag, err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	list = append(list, r.tags...)
+	return
+}
+
+// TagEnsure ensures the tag exists.
+func (r *Local) TagEnsure(tag *api.Tag) (err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for i := range r.tags {
+		if r.tags[i].Name == tag.Name && r.tags[i].Category.ID == tag.Category.ID {
+			*tag = r.tags[i]
+			return
+		}
+	}
+	tag.ID = r.newId()
+	for _, cat := range r.categories {
+		if cat.ID == tag.Category.ID {
+			tag.Category.Name = cat.Name
+		}
+	}
+	r.tags = append(r.tags, *tag)
+	return
+}
+
+// AppTagList returns the tags associated with the application (all sources).
+func (r *Local) AppTagList(appId uint) (list []api.TagRef, err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for source, refs := range r.appTags {
+		for _, ref := range refs {
+			ref.Source = source
+			list = append(list, ref)
+		}
+	}
+	return
+}
+
+// AppTagReplace replaces the tags associated with the application by source.
+func (r *Local) AppTagReplace(appId uint, source string, ids []uint) (err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	refs := []api.TagRef{}
+	for _, id := range ids {
+		refs = append(refs, api.TagRef{ID: id})
+	}
+	r.appTags[source] = refs
+	err = r.writeTags()
+	return
+}
+
+// client returns the (local) REST client used by the addon
+// adapter to fetch the task and update the task report.
+func (r *Local) client() (c *client.Stub) {
+	c = &client.Stub{
+		DoGet: func(p string, object any, params ...client.Param) (err error) {
+			if object, cast := object.(*api.Task); cast {
+				err = r.assign(object, r.task)
+				return
+			}
+			err = r.notFound(p)
+			return
+		},
+		DoPost: func(p string, object any) (err error) {
+			err = r.report(p, object)
+			return
+		},
+		DoPut: func(p string, object any, params ...client.Param) (err error) {
+			err = r.report(p, object)
+			return
+		},
+		DoDelete: func(p string, params ...client.Param) (err error) {
+			return
+		},
+		DoFilePost: func(p, source string, object any) (err error) {
+			r.mutex.Lock()
+			defer r.mutex.Unlock()
+			f, err := r.filePost(path.Base(p), source)
+			if err != nil {
+				return
+			}
+			err = r.assign(object, f)
+			return
+		},
+		DoFilePatch: func(p string, buffer []byte) (err error) {
+			r.mutex.Lock()
+			defer r.mutex.Unlock()
+			err = r.filePatch(p, buffer)
+			return
+		},
+	}
+	return
+}
+
+// filePost copies the (source) file to the output directory.
+// An empty file is created when the source is not specified.
+func (r *Local) filePost(name, source string) (f *api.File, err error) {
+	destination := path.Join(r.Output, name)
+	switch source {
+	case "":
+		err = os.WriteFile(destination, nil, 0644)
+	case destination:
+	default:
+		err = r.copy(source, destination)
+	}
+	if err != nil {
+		return
+	}
+	f = &api.File{Name: name, Path: destination}
+	f.ID = r.newId()
+	r.files[f.ID] = destination
+	return
+}
+
+// filePatch appends to the file.
+func (r *Local) filePatch(p string, buffer []byte) (err error) {
+	n, _ := strconv.Atoi(path.Base(p))
+	file, found := r.files[uint(n)]
+	if !found {
+		err = r.notFound(p)
+		return
+	}
+	f, err := os.OpenFile(file, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	_, err = f.Write(buffer)
+	return
+}
+
+// report writes the task report.
+func (r *Local) report(p string, object any) (err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if _, cast := object.(*api.TaskReport); !cast {
+		err = r.notFound(p)
+		return
+	}
+	err = r.writeYaml("report.yaml", object)
+	return
+}
+
+// writeTags writes the associated tags (category=name) by source.
+func (r *Local) writeTags() (err error) {
+	names := make(map[uint]string)
+	for _, tag := range r.tags {
+		names[tag.ID] = tag.Category.Name + "=" + tag.Name
+	}
+	m := make(map[string][]string)
+	for source, refs := range r.appTags {
+		tags := []string{}
+		for _, ref := range refs {
+			tags = append(tags, names[ref.ID])
+		}
+		sort.Strings(tags)
+		m[source] = tags
+	}
+	err = r.writeYaml("tags.yaml", m)
+	return
+}
+
+// copy a file.
+// When the destination is a directory, the file is copied into it.
+func (r *Local) copy(source, destination string) (err error) {
+	st, err := os.Stat(destination)
+	if err == nil && st.IsDir() {
+		destination = path.Join(destination, path.Base(source))
+	}
+	reader, err := os.Open(source)
+	if err != nil {
+		return
+	}
+	defer func() {
+		_ = reader.Close()
+	}()
+	writer, err := os.Create(destination)
+	if err != nil {
+		return
+	}
+	defer func() {
+		_ = writer.Close()
+	}()
+	_, err = io.Copy(writer, reader)
+	return
+}
+
+// newId returns the next ID.
+func (r *Local) newId() (id uint) {
+	id = r.nextId
+	r.nextId++
+	return
+}
+
+// assign the value to the (returned) object.
+func (r *Local) assign(object, value any) (err error) {
+	b, err := json.Marshal(value)
+	if err != nil {
+		return
+	}
+	err = json.Unmarshal(b, object)
+	return
+}
+
+// readYaml reads a YAML (or JSON) document.
+// The json tags are honored.
+func (r *Local) readYaml(p string, object any) (err error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	var doc any
+	err = yaml.NewDecoder(f).Decode(&doc)
+	if err != nil {
+		return
+	}
+	err = r.assign(object, doc)
+	return
+}
+
+// writeYaml writes a document to the output directory.
+func (r *Local) writeYaml(name string, object any) (err error) {
+	b, err := json.Marshal(object)
+	if err != nil {
+		return
+	}
+	var doc any
+	err = yaml.Unmarshal(b, &doc)
+	if err != nil {
+		return
+	}
+	f, err := os.Create(path.Join(r.Output, name))
+	if err != nil {
+		return
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	encoder := yaml.NewEncoder(f)
+	encoder.SetIndent(2)
+	err = encoder.Encode(doc)
+	if err != nil {
+		return
+	}
+	err = encoder.Close()
+	return
+}
+
+// notFound returns a NotFound error.
+func (r *Local) notFound(p string) (err error) {
+	err = &api.NotFound{
+		RestError: api.RestError{
+			Reason: "not found (local).",
+			Path:   p,
+		},
+	}
+	return
+}
+
+// LocalRepository local source directory.
+// Fetched (copied) into the source directory.
+type LocalRepository struct {
+	Source string
+	Path   string
+}
+
+// Id returns the source directory.
+func (r *LocalRepository) Id() (id string) {
+	id = r.Source
+	return
+}
+
+// Validate the source directory exists.
+func (r *LocalRepository) Validate() (err error) {
+	_, err = os.Stat(r.Source)
+	return
+}
+
+// Fetch copies the source directory.
+func (r *LocalRepository) Fetch() (err error) {
+	err = nas.MkDir(r.Path, 0755)
+	if err != nil {
+		return
+	}
+	err = nas.CpDir(r.Source+"/.", r.Path)
+	if err != nil {
+		return
+	}
+	addon.Activity("[LOCAL] using source: %s", r.Source)
+	return
+}
+
+// Update not supported.
+func (r *LocalRepository) Update() (err error) {
+	return
+}
+
+// Branch not supported.
+func (r *LocalRepository) Branch(ref string) (err error) {
+	return
+}
+
+// Commit not supported.
+func (r *LocalRepository) Commit(files []string, msg string) (err error) {
+	return
+}
+
+// Head returns an empty commit.
+func (r *LocalRepository) Head() (commit string, err error) {
+	return
+}
+
+// Clean not supported.
+func (r *LocalRepository) Clean() (err error) {
+	return
+}
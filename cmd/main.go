@@ -1,6 +1,7 @@
 package main
 
 import (
+	"errors"
 	"os"
 	"path"
 	"time"
@@ -14,17 +15,18 @@ import (
 )
 
 var (
-	addon     = hub.Addon
-	BinDir    = ""
-	SharedDir = ""
-	CacheDir  = ""
-	SourceDir = ""
-	Dir       = ""
-	M2Dir     = ""
-	RuleDir   = ""
-	OptDir    = ""
-	Source    = "Analysis"
-	Verbosity = 0
+	addon        = &SyncAddon{Adapter: hub.Addon}
+	BinDir       = ""
+	SharedDir    = ""
+	CacheDir     = ""
+	SourceDir    = ""
+	Dir          = ""
+	M2Dir        = ""
+	RuleDir      = ""
+	RuleCacheDir = ""
+	OptDir       = ""
+	Source       = "Analysis"
+	Verbosity    = 0
 )
 
 func init() {
@@ -36,6 +38,7 @@ func init() {
 	RuleDir = path.Join(Dir, "rules")
 	BinDir = path.Join(SharedDir, "bin")
 	M2Dir = path.Join(CacheDir, "m2")
+	RuleCacheDir = path.Join(CacheDir, "rules")
 }
 
 // Data Addon data passed in the secret.
@@ -52,81 +55,146 @@ type Data struct {
 	Rules Rules `json:"rules"`
 	// Tagger options.
 	Tagger Tagger `json:"tagger"`
+	// Sarif report the insights as an attached SARIF log.
+	Sarif bool `json:"sarif"`
+	// Diff report the diff with the previous analysis.
+	Diff bool `json:"diff"`
+	// Profiling attach the progress and rule profiling reports.
+	Profiling bool `json:"profiling"`
+	// Gate quality gate.
+	Gate Gate `json:"gate"`
+	// Health provider health options.
+	Health Health `json:"health"`
 }
 
 // main
+// The `local` command runs the addon in standalone mode.
 func main() {
-	addon.Run(func() (err error) {
-		addon.Activity("OptDir:    %s", OptDir)
-		addon.Activity("SharedDir: %s", SharedDir)
-		addon.Activity("CacheDir:  %s", CacheDir)
-		addon.Activity("SourceDir: %s", SourceDir)
-		addon.Activity("RuleDir:   %s", RuleDir)
-		addon.Activity("BinDir:    %s", BinDir)
-		addon.Activity("M2Dir:     %s", M2Dir)
-		//
-		// Get the addon data associated with the task.
-		d := &Data{}
-		err = addon.DataWith(d)
-		if err == nil {
-			Verbosity = d.Verbosity
-		} else {
-			return
-		}
-		//
-		// Create directories.
-		for _, dir := range []string{BinDir, M2Dir, RuleDir, OptDir} {
-			err = nas.MkDir(dir, 0755)
-			if err != nil {
-				return
-			}
-		}
-		//
-		// Fetch application.
-		addon.Activity("Fetching application.")
-		application, err := addon.Task.Application()
-		if err != nil {
-			return
-		}
-		//
-		// Apply profile.
-		err = applyProfile(d)
-		if err != nil {
-			return
-		}
-		//
-		// Build assets.
-		err = d.Mode.Build(application)
-		if err != nil {
-			return
-		}
-		err = d.Rules.Build()
+	if len(os.Args) > 1 && os.Args[1] == "local" {
+		UseLocal(os.Args[2:])
+	}
+	addon.Run(run)
+}
+
+// run the addon.
+func run() (err error) {
+	addon.Activity("OptDir:    %s", OptDir)
+	addon.Activity("SharedDir: %s", SharedDir)
+	addon.Activity("CacheDir:  %s", CacheDir)
+	addon.Activity("SourceDir: %s", SourceDir)
+	addon.Activity("RuleDir:   %s", RuleDir)
+	addon.Activity("BinDir:    %s", BinDir)
+	addon.Activity("M2Dir:     %s", M2Dir)
+	addon.Activity("RuleCache: %s", RuleCacheDir)
+	//
+	// Get the addon data associated with the task.
+	d := &Data{}
+	err = hubApi.DataWith(d)
+	if err == nil {
+		Verbosity = d.Verbosity
+	} else {
+		return
+	}
+	//
+	// Create directories.
+	for _, dir := range []string{BinDir, M2Dir, RuleDir, RuleCacheDir, OptDir} {
+		err = nas.MkDir(dir, 0755)
 		if err != nil {
 			return
 		}
-		//
-		// Run the analyzer.
+	}
+	//
+	// Evict stale cached rules.
+	cache := RuleCache{}
+	err = cache.Evict()
+	if err != nil {
+		addon.Activity("[RULESET] cache eviction failed: %s", err.Error())
+		err = nil
+	}
+	//
+	// Fetch application.
+	addon.Activity("Fetching application.")
+	application, err := hubApi.Application()
+	if err != nil {
+		return
+	}
+	//
+	// Apply profile.
+	err = applyProfile(d)
+	if err != nil {
+		return
+	}
+	//
+	// Build assets.
+	err = d.Mode.Build(application)
+	if err != nil {
+		return
+	}
+	err = d.Rules.Build()
+	if err != nil {
+		return
+	}
+	err = d.Mode.WithRules(application.ID, &d.Rules)
+	if err != nil {
+		return
+	}
+	//
+	// Dry-run.
+	if d.Mode.DryRun {
+		dryRun := DryRun{Data: d}
+		err = dryRun.Run()
+		return
+	}
+	//
+	// Run the analyzer.
 
-		analyzer := Analyzer{}
-		analyzer.Data = d
-		insights, deps, err := analyzer.Run()
+	analyzer := Analyzer{}
+	analyzer.Data = d
+	insights, deps, err := d.Mode.Analyze(&analyzer)
+	if err != nil {
+		return
+	}
+	d.Mode.Merge(insights)
+	//
+	// RuleError
+	ruleErr := insights.RuleError()
+	ruleErr.Report()
+	//
+	// Diff with the previous analysis.
+	var diff *builder.Diff
+	if (d.Diff || d.Gate.NeedsDiff()) && !d.Mode.Discovery {
+		diff, err = diffAnalysis(application.ID)
 		if err != nil {
 			return
 		}
-		//
-		// RuleError
-		ruleErr := insights.RuleError()
-		ruleErr.Report()
-		//
-		// Update application.
-		err = updateApplication(d, application.ID, insights, deps)
+		insights.WithVisitors(diff)
+	}
+	//
+	// Update application.
+	err = updateApplication(d, application.ID, insights, deps)
+	if err != nil {
+		return
+	}
+	if diff != nil {
+		err = attachDiff(diff)
 		if err != nil {
 			return
 		}
-
-		addon.Activity("Done.")
+	}
+	//
+	// Quality gate.
+	gateErr := d.Gate.Evaluate(insights, diff)
+	if gateErr != nil {
+		var gErr *GateError
+		if errors.As(gateErr, &gErr) {
+			addon.Error(gErr.Violations...)
+		}
+		err = gateErr
 		return
-	})
+	}
+
+	addon.Activity("Done.")
+	return
 }
 
 // applyProfile fetch and apply profile when specified.
@@ -137,7 +205,7 @@ func applyProfile(d *Data) (err error) {
 	d.Mode = Data{}.Mode
 	d.Scope = Data{}.Scope
 	d.Rules = Data{}.Rules
-	p, err := addon.AnalysisProfile.Get(d.Profile.ID)
+	p, err := hubApi.Profile(d.Profile.ID)
 	if err != nil {
 		return
 	}
@@ -180,7 +248,29 @@ func updateApplication(d *Data, appId uint, insights *builder.Insights, deps *bu
 			return
 		}
 	}
+	//
+	// Reports built as the insights are walked.
+	evidence := &builder.Evidence{}
+	if d.Tagger.Enabled {
+		insights.WithVisitors(evidence)
+	}
+	sarif := &builder.Sarif{}
+	if d.Sarif && !d.Mode.Discovery {
+		insights.WithVisitors(sarif)
+	}
 	if d.Mode.Discovery {
+		insights.Visit()
+		if d.Tagger.Enabled {
+			err = d.Tagger.Attach(evidence.Tags)
+			if err != nil {
+				return
+			}
+		}
+		discovery := Discovery{Data: d}
+		err = hubApi.FactReplace(appId, DiscoverySource, discovery.Facts(insights))
+		if err == nil {
+			addon.Activity("Facts updated.")
+		}
 		return
 	}
 	//
@@ -201,19 +291,59 @@ func updateApplication(d *Data, appId uint, insights *builder.Insights, deps *bu
 		return
 	}
 	mark := time.Now()
-	reported, err := addon.Application.
-		Select(appId).
-		Analysis.
-		Upload(manifest.Path, api.MIMEYAML)
+	reported, err := hubApi.AnalysisUpload(appId, manifest.Path)
 	if err != nil {
 		return
 	}
 	addon.Activity("Analysis %d reported. duration: %s", reported.ID, time.Since(mark))
+	// Analysis report.
+	report := builder.AnalysisReport{
+		Unavailable: d.Health.degraded.Providers,
+		Provenance:  d.Rules.Provenance(),
+	}
+	if !report.Empty() {
+		err = report.Write()
+		if err != nil {
+			return
+		}
+		f, pErr := hubApi.FilePost(report.Path)
+		if pErr != nil {
+			err = pErr
+			return
+		}
+		addon.Attach(f)
+		addon.Activity("Analysis report attached.")
+	}
+	// Tag evidence.
+	if d.Tagger.Enabled {
+		err = d.Tagger.Attach(evidence.Tags)
+		if err != nil {
+			return
+		}
+	}
+	// SARIF.
+	if d.Sarif {
+		err = sarif.Write()
+		if err != nil {
+			return
+		}
+		f, pErr := hubApi.FilePost(sarif.Path)
+		if pErr != nil {
+			err = pErr
+			return
+		}
+		addon.Attach(f)
+		addon.Activity("SARIF log attached.")
+	}
 	// Facts.
-	err = addon.Application.Select(appId).
-		Fact.
-		Source(Source).
-		Replace(insights.Facts())
+	facts := insights.Facts()
+	if digest := d.Rules.Fingerprint(); digest != "" {
+		facts[FactRules] = RulesFingerprint{
+			Analysis: reported.ID,
+			Digest:   digest,
+		}
+	}
+	err = hubApi.FactReplace(appId, Source, facts)
 	if err == nil {
 		addon.Activity("Facts updated.")
 	}
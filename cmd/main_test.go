@@ -0,0 +1,135 @@
+package main
+
+import (
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/konveyor/tackle2-addon-analyzer/builder"
+	"github.com/konveyor/tackle2-hub/shared/api"
+	"github.com/onsi/gomega"
+	"gopkg.in/yaml.v2"
+)
+
+func TestMainFlow(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	tmp := useDirs(t)
+	write := func(p, content string) (fp string) {
+		fp = path.Join(tmp, "input", p)
+		err := os.MkdirAll(path.Dir(fp), 0755)
+		g.Expect(err).To(gomega.BeNil())
+		err = os.WriteFile(fp, []byte(content), 0644)
+		g.Expect(err).To(gomega.BeNil())
+		return
+	}
+	write("src/a.txt", "hello TODO world\n")
+	rules := write(
+		"rules.yaml",
+		`
+- ruleID: todo-00001
+  description: TODO found
+  category: mandatory
+  effort: 3
+  labels:
+  - konveyor.io/target=demo
+  tag:
+  - Demo=Todo
+  when:
+    builtin.filecontent:
+      pattern: TODO
+  message: TODO found
+`)
+	fake := &FakeHub{
+		Data: api.Map{
+			"tagger": api.Map{"enabled": true},
+			"sarif":  true,
+			"rules": api.Map{
+				"labels": api.Map{
+					"included": []string{"konveyor.io/target=demo"},
+				},
+			},
+		},
+		App: api.Application{
+			Resource: api.Resource{ID: 1},
+			Name:     "demo",
+			Repository: &api.Repository{
+				URL: "https://git.example.com/demo.git",
+			},
+		},
+		AddonDef: api.Addon{
+			Name: "analyzer",
+			Extensions: []api.Extension{
+				{
+					Name:  "builtin",
+					Addon: "analyzer",
+					Metadata: api.Map{
+						"provider": api.Map{
+							"name": "builtin",
+							"initConfig": []api.Map{
+								{"location": "$(builtin.location)"},
+							},
+						},
+					},
+				},
+			},
+		},
+		RuleSetList: []api.RuleSet{
+			{
+				Resource: api.Resource{ID: 1},
+				Name:     "demo",
+				Rules: []api.Rule{
+					{
+						Name:   "rules.yaml",
+						Labels: []string{"konveyor.io/target=demo"},
+						File:   &api.Ref{ID: 1, Name: "rules.yaml"},
+					},
+				},
+			},
+		},
+		Files: map[uint]string{1: rules},
+		Repositories: map[string]string{
+			"https://git.example.com/demo.git": path.Join(tmp, "input", "src"),
+		},
+		nextId: 100,
+	}
+	useFakeHub(t, fake)
+
+	err := run()
+	g.Expect(err).To(gomega.BeNil())
+	// analysis
+	g.Expect(len(fake.Uploaded)).To(gomega.Equal(1))
+	g.Expect(fake.Uploaded[0]).To(gomega.ContainSubstring("rule: todo-00001"))
+	g.Expect(fake.Uploaded[0]).To(gomega.ContainSubstring("commit: abc123"))
+	// attached
+	g.Expect(fake.Posted).To(gomega.HaveKey("settings.yaml"))
+	g.Expect(fake.Posted).To(gomega.HaveKey("insights.sarif"))
+	g.Expect(fake.Posted).NotTo(gomega.HaveKey("profiling.yaml"))
+	g.Expect(fake.Posted).To(gomega.HaveKey("progress.yaml"))
+	g.Expect(fake.Posted["progress.yaml"]).To(gomega.ContainSubstring("name: todo-00001"))
+	g.Expect(strings.Contains(fake.Posted["insights.sarif"], "todo-00001")).To(gomega.BeTrue())
+	// tags
+	g.Expect(fake.TagNames(Source)).To(gomega.Equal([]string{"Demo=Todo"}))
+	g.Expect(fake.Posted).To(gomega.HaveKey("tag-evidence.yaml"))
+	var reports []TagReport
+	err = yaml.Unmarshal([]byte(fake.Posted["tag-evidence.yaml"]), &reports)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(reports)).To(gomega.Equal(1))
+	g.Expect(reports[0].Source).To(gomega.Equal(Source))
+	g.Expect(reports[0].Tag).To(gomega.Equal("Demo=Todo"))
+	g.Expect(reports[0].Evidence).To(gomega.ContainElement(
+		builder.TagEvidence{
+			RuleSet: "rulesets-1-rules",
+			Rule:    "todo-00001",
+			File:    path.Join(tmp, "shared", "source", "demo", "a.txt"),
+			Line:    1,
+		}))
+	// facts
+	facts := fake.Facts[Source]
+	g.Expect(facts).ToNot(gomega.BeNil())
+	g.Expect(facts["effort"]).To(gomega.HaveKeyWithValue("total", 3))
+	fingerprint, cast := facts[FactRules].(RulesFingerprint)
+	g.Expect(cast).To(gomega.BeTrue())
+	g.Expect(fingerprint.Analysis).ToNot(gomega.BeZero())
+	g.Expect(fingerprint.Digest).ToNot(gomega.BeEmpty())
+}
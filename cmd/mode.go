@@ -5,24 +5,29 @@ import (
 	"path"
 	"strings"
 
-	"github.com/konveyor/analyzer-lsp/core"
 	"github.com/konveyor/analyzer-lsp/provider"
+	"github.com/konveyor/tackle2-addon-analyzer/builder"
 	"github.com/konveyor/tackle2-hub/shared/addon/scm"
 	"github.com/konveyor/tackle2-hub/shared/api"
 )
 
 // Mode settings.
 type Mode struct {
-	Discovery  bool   `json:"discovery"`
-	Binary     bool   `json:"binary"`
-	Artifact   string `json:"artifact"`
-	WithDeps   bool   `json:"withDeps"`
-	Repository scm.SCM
+	Discovery   bool    `json:"discovery"`
+	Binary      bool    `json:"binary"`
+	Artifact    string  `json:"artifact"`
+	WithDeps    bool    `json:"withDeps"`
+	Incremental bool    `json:"incremental"`
+	Modules     Modules `json:"modules"`
+	DryRun      bool    `json:"dryRun"`
+	Repository  scm.SCM
 	//
 	path struct {
-		appDir string
-		binary string
+		appDir  string
+		binary  string
+		modules []string
 	}
+	incremental Incremental
 }
 
 // With populates with profile.
@@ -35,6 +40,21 @@ func (r *Mode) With(p *api.ApMode) (err error) {
 func (r *Mode) Build(application *api.Application) (err error) {
 	if !r.Binary {
 		err = r.fetchRepository(application)
+		if err != nil {
+			return
+		}
+		if r.Modules.Enabled() {
+			r.path.modules, err = r.Modules.Build(r.path.appDir)
+			if err != nil {
+				return
+			}
+		}
+		if r.Incremental {
+			err = r.incremental.Build(
+				application.ID,
+				r.Repository,
+				r.path.appDir)
+		}
 		return
 	}
 	if r.Artifact != "" {
@@ -47,14 +67,23 @@ func (r *Mode) Build(application *api.Application) (err error) {
 	return
 }
 
-// AddOptions adds analyzer options.
-func (r *Mode) ToOption() (option core.AnalyzerOption) {
-	if r.WithDeps {
+// WithRules falls back to full analysis (incremental) when the
+// rules changed since the previous analysis.
+func (r *Mode) WithRules(appId uint, rules *Rules) (err error) {
+	err = r.incremental.WithRules(appId, rules.Fingerprint())
+	return
+}
+
+// AnalysisMode returns the (default) provider analysis mode.
+// Providers may override the mode in the extension metadata.
+// Discovery always uses source analysis mode.
+func (r *Mode) AnalysisMode() (mode provider.AnalysisMode) {
+	if r.WithDeps && !r.Discovery {
 		addon.Activity("[ANAYLZER] using full analysis mode")
-		option = core.WithAnalysisMode(string(provider.FullAnalysisMode))
+		mode = provider.FullAnalysisMode
 	} else {
 		addon.Activity("[ANAYLZER] using source analysis mode")
-		option = core.WithAnalysisMode(string(provider.SourceOnlyAnalysisMode))
+		mode = provider.SourceOnlyAnalysisMode
 	}
 	return
 }
@@ -69,19 +98,50 @@ func (r *Mode) Location() (path string) {
 	return
 }
 
+// Locations returns the locations to be analyzed.
+// Each (module) location is a provider root.
+func (r *Mode) Locations() (paths []string) {
+	if len(r.path.modules) > 0 {
+		paths = r.path.modules
+	} else {
+		paths = []string{r.Location()}
+	}
+	return
+}
+
+// ModuleNames returns the module (location => name) map.
+func (r *Mode) ModuleNames() (names map[string]string) {
+	if len(r.path.modules) > 0 {
+		names = r.Modules.Names(r.path.appDir, r.path.modules)
+	}
+	return
+}
+
+// Analyze runs the analyzer.
+// When the analyzer is not required (incremental), the
+// previous analysis is reused.
+func (r *Mode) Analyze(analyzer *Analyzer) (insights *builder.Insights, deps *builder.Deps, err error) {
+	if !r.incremental.Required() {
+		insights, deps, err = r.incremental.Reuse()
+		return
+	}
+	insights, deps, err = analyzer.Run()
+	return
+}
+
+// Merge merges the insights reported by the previous
+// analysis when incremental analysis was performed.
+func (r *Mode) Merge(insights *builder.Insights) {
+	r.incremental.Merge(insights)
+}
+
 // fetchRepository get SCM repository.
 func (r *Mode) fetchRepository(application *api.Application) (err error) {
 	if application.Repository == nil {
 		err = errors.New("Application repository not defined.")
 		return
 	}
-	identity, _, err :=
-		addon.Application.Select(application.ID).Identity.
-			Decrypted().
-			Search().
-			Direct("source").
-			Indirect("source").
-			Find()
+	identity, _, err := hubApi.AppIdentity(application.ID, "source")
 	if err != nil {
 		return
 	}
@@ -92,7 +152,7 @@ func (r *Mode) fetchRepository(application *api.Application) (err error) {
 				application.Repository.URL),
 			".")[0])
 	r.path.appDir = path.Join(SourceDir, application.Repository.Path)
-	r.Repository, err = scm.New(
+	r.Repository, err = hubApi.Repository(
 		SourceDir,
 		*application.Repository,
 		identity)
@@ -105,8 +165,7 @@ func (r *Mode) fetchRepository(application *api.Application) (err error) {
 
 // getArtifact get uploaded artifact.
 func (r *Mode) getArtifact() (err error) {
-	bucket := addon.Bucket()
-	err = bucket.Get(r.Artifact, BinDir)
+	err = hubApi.BucketGet(r.Artifact, BinDir)
 	r.path.binary = path.Join(BinDir, path.Base(r.Artifact))
 	return
 }
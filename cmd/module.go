@@ -0,0 +1,134 @@
+package main
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// BuildFiles files that identify a module (build) root.
+var BuildFiles = []string{
+	"pom.xml",
+	"package.json",
+	"go.mod",
+}
+
+// Modules options.
+// Paths are relative to the application (source) directory.
+type Modules struct {
+	Paths    []string `json:"paths"`
+	Discover bool     `json:"discover"`
+}
+
+// Enabled returns true when multi-location analysis is requested.
+func (r *Modules) Enabled() (b bool) {
+	b = len(r.Paths) > 0 || r.Discover
+	return
+}
+
+// Build returns the module locations within the application directory.
+// The application directory is returned when no modules are found.
+func (r *Modules) Build(appDir string) (locations []string, err error) {
+	found := make(map[string]bool)
+	for _, p := range r.Paths {
+		p = path.Join(appDir, path.Clean("/"+p))
+		st, nErr := os.Stat(p)
+		if nErr != nil {
+			err = nErr
+			return
+		}
+		if !st.IsDir() {
+			p = path.Dir(p)
+		}
+		found[p] = true
+	}
+	if r.Discover {
+		var discovered []string
+		discovered, err = r.discover(appDir)
+		if err != nil {
+			return
+		}
+		for _, p := range discovered {
+			found[p] = true
+		}
+	}
+	for p := range found {
+		locations = append(locations, p)
+	}
+	sort.Strings(locations)
+	if len(locations) == 0 {
+		locations = []string{appDir}
+	}
+	for _, p := range locations {
+		addon.Activity("[MODULE] %s", p)
+	}
+	return
+}
+
+// Names returns the module (location => name) map.
+// The name is the location relative to the application directory.
+func (r *Modules) Names(appDir string, locations []string) (names map[string]string) {
+	names = make(map[string]string)
+	for _, p := range locations {
+		name, err := filepath.Rel(appDir, p)
+		if err != nil {
+			name = p
+		}
+		names[p] = name
+	}
+	return
+}
+
+// discover walks the application directory and returns the
+// directories containing a build file. Aggregate (parent) roots
+// that contain other roots are omitted so that each module is
+// analyzed once.
+func (r *Modules) discover(appDir string) (roots []string, err error) {
+	var found []string
+	err = filepath.WalkDir(
+		appDir,
+		func(p string, d fs.DirEntry, wErr error) (err error) {
+			if wErr != nil {
+				err = wErr
+				return
+			}
+			if !d.IsDir() {
+				return
+			}
+			name := d.Name()
+			if p != appDir && (strings.HasPrefix(name, ".") ||
+				name == "node_modules" ||
+				name == "vendor" ||
+				name == "target") {
+				err = filepath.SkipDir
+				return
+			}
+			for _, f := range BuildFiles {
+				_, nErr := os.Stat(path.Join(p, f))
+				if nErr == nil {
+					found = append(found, p)
+					break
+				}
+			}
+			return
+		})
+	if err != nil {
+		return
+	}
+	for _, p := range found {
+		parent := false
+		for _, q := range found {
+			if strings.HasPrefix(q, p+"/") {
+				parent = true
+				break
+			}
+		}
+		if !parent {
+			roots = append(roots, p)
+		}
+	}
+	return
+}
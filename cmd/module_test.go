@@ -0,0 +1,109 @@
+package main
+
+import (
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/konveyor/tackle2-hub/shared/api"
+	"github.com/onsi/gomega"
+)
+
+func TestModules(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	tmp := useDirs(t)
+	write := func(p, content string) (fp string) {
+		fp = path.Join(tmp, "input", p)
+		err := os.MkdirAll(path.Dir(fp), 0755)
+		g.Expect(err).To(gomega.BeNil())
+		err = os.WriteFile(fp, []byte(content), 0644)
+		g.Expect(err).To(gomega.BeNil())
+		return
+	}
+	write("src/go.mod", "module parent\n")
+	write("src/a/go.mod", "module a\n")
+	write("src/a/a.txt", "hello TODO world\n")
+	write("src/b/package.json", "{}\n")
+	write("src/b/b.txt", "hello TODO world\n")
+	write("src/node_modules/c/package.json", "{}\n")
+	rules := write(
+		"rules.yaml",
+		`
+- ruleID: todo-00001
+  description: TODO found
+  labels:
+  - konveyor.io/target=demo
+  when:
+    builtin.filecontent:
+      pattern: TODO
+  message: TODO found
+`)
+	fake := &FakeHub{
+		Data: api.Map{
+			"mode": api.Map{
+				"modules": api.Map{"discover": true},
+			},
+			"rules": api.Map{
+				"labels": api.Map{
+					"included": []string{"konveyor.io/target=demo"},
+				},
+			},
+		},
+		App: api.Application{
+			Resource: api.Resource{ID: 1},
+			Name:     "demo",
+			Repository: &api.Repository{
+				URL: "https://git.example.com/demo.git",
+			},
+		},
+		AddonDef: api.Addon{
+			Name: "analyzer",
+			Extensions: []api.Extension{
+				{
+					Name:  "builtin",
+					Addon: "analyzer",
+					Metadata: api.Map{
+						"provider": api.Map{
+							"name": "builtin",
+							"initConfig": []api.Map{
+								{"location": "$(builtin.location)"},
+							},
+						},
+					},
+				},
+			},
+		},
+		RuleSetList: []api.RuleSet{
+			{
+				Resource: api.Resource{ID: 1},
+				Name:     "demo",
+				Rules: []api.Rule{
+					{
+						Name:   "rules.yaml",
+						Labels: []string{"konveyor.io/target=demo"},
+						File:   &api.Ref{ID: 1, Name: "rules.yaml"},
+					},
+				},
+			},
+		},
+		Files: map[uint]string{1: rules},
+		Repositories: map[string]string{
+			"https://git.example.com/demo.git": path.Join(tmp, "input", "src"),
+		},
+		nextId: 100,
+	}
+	useFakeHub(t, fake)
+
+	err := run()
+	g.Expect(err).To(gomega.BeNil())
+	// provider initialized per module.
+	settings := fake.Posted["settings.yaml"]
+	g.Expect(strings.Count(settings, "location:")).To(gomega.Equal(2))
+	g.Expect(settings).To(gomega.ContainSubstring("/demo/a"))
+	g.Expect(settings).To(gomega.ContainSubstring("/demo/b"))
+	// incidents tagged with the module.
+	g.Expect(len(fake.Uploaded)).To(gomega.Equal(1))
+	g.Expect(fake.Uploaded[0]).To(gomega.ContainSubstring("module: a"))
+	g.Expect(fake.Uploaded[0]).To(gomega.ContainSubstring("module: b"))
+}
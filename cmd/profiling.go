@@ -0,0 +1,162 @@
+package main
+
+import (
+	"encoding/csv"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	output "github.com/konveyor/analyzer-lsp/output/v1/konveyor"
+	"gopkg.in/yaml.v2"
+)
+
+// Rule status.
+const (
+	RuleStatusMatched   = "matched"
+	RuleStatusUnmatched = "unmatched"
+	RuleStatusError     = "error"
+	RuleStatusSkipped   = "skipped"
+)
+
+// RuleProfile rule profile.
+type RuleProfile struct {
+	RuleSet string `yaml:"ruleset"`
+	Rule    string `yaml:"rule"`
+	Status  string `yaml:"status"`
+	// Duration the wall time.
+	Duration  string `yaml:"duration"`
+	Incidents int    `yaml:"incidents"`
+	// Calls the provider calls made to evaluate the rule.
+	// Each provider condition is a call.
+	Calls     int      `yaml:"calls"`
+	Providers []string `yaml:"providers,omitempty"`
+	//
+	duration time.Duration
+}
+
+// Profiling rule performance profiling report.
+// Sorted by wall time (descending) so pathological rules are listed first.
+// The wall time is reported (by the analyzer) by rule id so rules with the
+// same id in different rulesets share the time.
+type Profiling struct {
+	Rules []RuleProfile `yaml:"rules"`
+}
+
+// Build the report.
+// rules: the parsed rule files (keyed by ruleset.rule).
+// timing: the wall time by rule id.
+func (r *Profiling) Build(
+	results []output.RuleSet,
+	rules []RuleFile,
+	timing map[string]time.Duration) {
+	conditions := make(map[string]map[string]int)
+	for _, rule := range rules {
+		conditions[rule.Key()] = rule.Conditions
+	}
+	add := func(ruleset, ruleid, status string, incidents int) {
+		d := timing[ruleid]
+		p := RuleProfile{
+			RuleSet:   ruleset,
+			Rule:      ruleid,
+			Status:    status,
+			Duration:  d.String(),
+			Incidents: incidents,
+			duration:  d,
+		}
+		for name, n := range conditions[ruleset+"."+ruleid] {
+			p.Calls += n
+			p.Providers = append(p.Providers, name)
+		}
+		sort.Strings(p.Providers)
+		r.Rules = append(r.Rules, p)
+	}
+	for _, ruleset := range results {
+		for ruleid, v := range ruleset.Violations {
+			add(ruleset.Name, ruleid, RuleStatusMatched, len(v.Incidents))
+		}
+		for ruleid, v := range ruleset.Insights {
+			add(ruleset.Name, ruleid, RuleStatusMatched, len(v.Incidents))
+		}
+		for ruleid := range ruleset.Errors {
+			add(ruleset.Name, ruleid, RuleStatusError, 0)
+		}
+		for _, ruleid := range ruleset.Unmatched {
+			add(ruleset.Name, ruleid, RuleStatusUnmatched, 0)
+		}
+		for _, ruleid := range ruleset.Skipped {
+			add(ruleset.Name, ruleid, RuleStatusSkipped, 0)
+		}
+	}
+	sort.Slice(
+		r.Rules,
+		func(i, j int) bool {
+			a := r.Rules[i]
+			b := r.Rules[j]
+			if a.duration != b.duration {
+				return a.duration > b.duration
+			}
+			if a.RuleSet != b.RuleSet {
+				return a.RuleSet < b.RuleSet
+			}
+			return a.Rule < b.Rule
+		})
+}
+
+// Write the report (YAML and CSV) to the directory.
+func (r *Profiling) Write(dir string) (paths []string, err error) {
+	p := path.Join(dir, "profiling.yaml")
+	b, err := yaml.Marshal(r)
+	if err != nil {
+		return
+	}
+	err = os.WriteFile(p, b, 0644)
+	if err != nil {
+		return
+	}
+	paths = append(paths, p)
+	p = path.Join(dir, "profiling.csv")
+	err = r.writeCSV(p)
+	if err != nil {
+		return
+	}
+	paths = append(paths, p)
+	return
+}
+
+// writeCSV writes the CSV file.
+func (r *Profiling) writeCSV(p string) (err error) {
+	f, err := os.Create(p)
+	if err != nil {
+		return
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	writer := csv.NewWriter(f)
+	_ = writer.Write([]string{
+		"ruleset",
+		"rule",
+		"status",
+		"duration_ms",
+		"incidents",
+		"calls",
+		"providers",
+	})
+	for _, rule := range r.Rules {
+		_ = writer.Write([]string{
+			rule.RuleSet,
+			rule.Rule,
+			rule.Status,
+			strconv.FormatInt(rule.duration.Milliseconds(), 10),
+			strconv.Itoa(rule.Incidents),
+			strconv.Itoa(rule.Calls),
+			strings.Join(rule.Providers, ";"),
+		})
+	}
+	writer.Flush()
+	err = writer.Error()
+	return
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// RuleFile a parsed rule.
+type RuleFile struct {
+	// Dir the directory containing the rule file.
+	Dir string
+	// RuleSet name.
+	RuleSet string
+	// ID rule id.
+	ID string
+	// Labels rule labels.
+	Labels []string
+	// Conditions number of conditions by provider.
+	Conditions map[string]int
+}
+
+// RuleSetFile a parsed ruleset.
+type RuleSetFile struct {
+	Name   string   `yaml:"name"`
+	Labels []string `yaml:"labels"`
+}
+
+// Key returns ruleset.ruleid.
+func (r *RuleFile) Key() (k string) {
+	k = r.ID
+	if r.RuleSet != "" {
+		k = r.RuleSet + "." + r.ID
+	}
+	return
+}
+
+// RuleFiles rule files.
+type RuleFiles struct {
+	// Paths to rule files and directories.
+	Paths []string
+}
+
+// Rules returns the parsed rules.
+// Files that cannot be parsed as rules are ignored.
+func (r *RuleFiles) Rules() (rules []RuleFile, err error) {
+	for _, root := range r.Paths {
+		err = filepath.WalkDir(
+			root,
+			func(p string, d fs.DirEntry, wErr error) (err error) {
+				if wErr != nil {
+					err = wErr
+					return
+				}
+				if d.IsDir() {
+					return
+				}
+				ext := filepath.Ext(p)
+				if ext != ".yaml" && ext != ".yml" {
+					return
+				}
+				if strings.HasPrefix(filepath.Base(p), "ruleset.") {
+					return
+				}
+				rules = append(rules, r.parse(p)...)
+				return
+			})
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+// parse the rules in the file.
+func (r *RuleFiles) parse(path string) (rules []RuleFile) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+	var parsed []map[string]any
+	err = yaml.Unmarshal(b, &parsed)
+	if err != nil {
+		return
+	}
+	dir := filepath.Dir(path)
+	ruleset := r.RuleSet(dir)
+	for _, m := range parsed {
+		rule := RuleFile{
+			Dir:        dir,
+			RuleSet:    ruleset.Name,
+			ID:         fmt.Sprintf("%v", m["ruleID"]),
+			Conditions: make(map[string]int),
+		}
+		if list, cast := m["labels"].([]any); cast {
+			for _, label := range list {
+				rule.Labels = append(rule.Labels, fmt.Sprintf("%v", label))
+			}
+		}
+		r.conditions(m["when"], rule.Conditions)
+		rules = append(rules, rule)
+	}
+	return
+}
+
+// RuleSet returns the ruleset defined in the directory.
+func (r *RuleFiles) RuleSet(dir string) (ruleset RuleSetFile) {
+	for _, f := range []string{"ruleset.yaml", "ruleset.yml"} {
+		b, err := os.ReadFile(filepath.Join(dir, f))
+		if err != nil {
+			continue
+		}
+		err = yaml.Unmarshal(b, &ruleset)
+		if err == nil {
+			break
+		}
+	}
+	return
+}
+
+// conditions counts the (provider.capability) conditions by provider.
+func (r *RuleFiles) conditions(node any, counted map[string]int) {
+	switch node := node.(type) {
+	case map[any]any:
+		for k, v := range node {
+			key := fmt.Sprintf("%v", k)
+			name, _, found := strings.Cut(key, ".")
+			if found {
+				counted[name]++
+				continue
+			}
+			r.conditions(v, counted)
+		}
+	case []any:
+		for _, v := range node {
+			r.conditions(v, counted)
+		}
+	}
+}
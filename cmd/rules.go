@@ -1,6 +1,9 @@
 package main
 
 import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
 	"io/fs"
 	"maps"
 	"os"
@@ -15,7 +18,8 @@ import (
 	"github.com/konveyor/analyzer-lsp/core"
 	"github.com/konveyor/analyzer-lsp/engine/labels"
 	"github.com/konveyor/analyzer-lsp/parser"
-	"github.com/konveyor/tackle2-hub/shared/addon/scm"
+	"github.com/konveyor/tackle2-addon-analyzer/builder"
+	"github.com/konveyor/tackle2-hub/shared/addon/command"
 	"github.com/konveyor/tackle2-hub/shared/api"
 	"github.com/konveyor/tackle2-hub/shared/nas"
 	"gopkg.in/yaml.v3"
@@ -33,14 +37,38 @@ var LvRegex = regexp.MustCompile(`(\D+)(\d(?:[\d\.]*\d)?)([\+-])?$`)
 
 // Rules settings.
 type Rules struct {
-	Path         string          `json:"path"`
-	Repository   *api.Repository `json:"repository"`
-	Identity     *api.Ref        `json:"identity"`
-	Labels       Labels          `json:"labels"`
-	RuleSets     []api.Ref       `json:"ruleSets"`
+	Path       string          `json:"path"`
+	Repository *api.Repository `json:"repository"`
+	Identity   *api.Ref        `json:"identity"`
+	Labels     Labels          `json:"labels"`
+	RuleSets   []api.Ref       `json:"ruleSets"`
+	// Commits rules repository URL => pinned commit.
+	Commits      map[string]string `json:"commits"`
 	ruleFiles    []api.Ref
 	repositories []string
 	rules        []string
+	provenance   builder.Provenance
+}
+
+// PinError reports a rules repository cannot be pinned to the commit.
+type PinError struct {
+	URL    string
+	Commit string
+	Reason string
+}
+
+func (e *PinError) Error() (s string) {
+	return fmt.Sprintf(
+		"Rules repository: %s cannot be pinned to commit: %s. %s",
+		e.URL,
+		e.Commit,
+		e.Reason)
+}
+
+func (e *PinError) Is(err error) (matched bool) {
+	var inst *PinError
+	matched = errors.As(err, &inst)
+	return
 }
 
 func (r *Rules) With(p *api.ApRules) (err error) {
@@ -48,7 +76,7 @@ func (r *Rules) With(p *api.ApRules) (err error) {
 	r.Repository = p.Repository
 	var target *api.Target
 	for _, ref := range p.Targets {
-		target, err = addon.Target.Get(ref.ID)
+		target, err = hubApi.Target(ref.ID)
 		if err != nil {
 			return
 		}
@@ -125,24 +153,42 @@ func (r *Rules) addFiles() (err error) {
 	r.repositories = append(r.repositories, ruleDir)
 	if len(r.ruleFiles) > 0 {
 		for _, ref := range r.ruleFiles {
-			fileId := strconv.Itoa(int(ref.ID))
-			dest := filepath.Join(ruleDir, fileId)
-			addon.Activity(
-				"[RULESET] fetching file: (id=%d) => %s",
-				ref.ID,
-				dest)
-			err = addon.File.Get(ref.ID, dest)
-			if err != nil {
+			r.provenance.Files = append(
+				r.provenance.Files,
+				builder.RuleFile{
+					ID:   ref.ID,
+					Name: ref.Name,
+				})
+		}
+		cache := RuleCache{}
+		err = cache.Get(
+			cache.FilesKey(r.ruleFiles),
+			ruleDir,
+			func(dir string) (err error) {
+				for _, ref := range r.ruleFiles {
+					fileId := strconv.Itoa(int(ref.ID))
+					dest := filepath.Join(dir, fileId)
+					addon.Activity(
+						"[RULESET] fetching file: (id=%d) => %s",
+						ref.ID,
+						dest)
+					err = hubApi.FileGet(ref.ID, dest)
+					if err != nil {
+						return
+					}
+				}
 				return
-			}
+			})
+		if err != nil {
+			return
 		}
 	} else {
 		if r.Path != "" {
+			r.provenance.Bucket = r.Path
 			addon.Activity(
 				"[RULESET] fetching bucket: %s",
 				r.Path)
-			bucket := addon.Bucket()
-			err = bucket.Get(r.Path, ruleDir)
+			err = hubApi.BucketGet(r.Path, ruleDir)
 			if err != nil {
 				return
 			}
@@ -157,7 +203,7 @@ func (r *Rules) addRuleSets() (err error) {
 	ruleSets := make([]api.RuleSet, 0)
 	for _, ref := range r.RuleSets {
 		var ruleSet *api.RuleSet
-		ruleSet, err = addon.RuleSet.Get(ref.ID)
+		ruleSet, err = hubApi.RuleSet(ref.ID)
 		if err != nil {
 			return
 		}
@@ -203,7 +249,7 @@ func (r *Rules) addDeps(ruleSet *api.RuleSet, history History) (err error) {
 		}
 		history[ref.ID] = 0
 		var ruleSet *api.RuleSet
-		ruleSet, err = addon.RuleSet.Get(ref.ID)
+		ruleSet, err = hubApi.RuleSet(ref.ID)
 		if err != nil {
 			return
 		}
@@ -243,18 +289,36 @@ func (r *Rules) addRules(ruleset *api.RuleSet) (err error) {
 		return
 	}
 	r.rules = append(r.rules, ruleDir)
-	for _, ruleset := range ruleset.Rules {
-		file := ruleset.File
-		if file == nil {
-			continue
-		}
-		err = addon.File.Get(
-			ruleset.File.ID,
-			path.Join(ruleDir, file.Name))
-		if err != nil {
-			break
-		}
-	}
+	for _, rule := range ruleset.Rules {
+		if rule.File != nil {
+			r.provenance.Files = append(
+				r.provenance.Files,
+				builder.RuleFile{
+					RuleSet: ruleset.ID,
+					ID:      rule.File.ID,
+					Name:    rule.File.Name,
+				})
+		}
+	}
+	cache := RuleCache{}
+	err = cache.Get(
+		cache.RuleSetKey(ruleset),
+		ruleDir,
+		func(dir string) (err error) {
+			for _, rule := range ruleset.Rules {
+				file := rule.File
+				if file == nil {
+					continue
+				}
+				err = hubApi.FileGet(
+					file.ID,
+					path.Join(dir, file.Name))
+				if err != nil {
+					return
+				}
+			}
+			return
+		})
 	return
 }
 
@@ -274,19 +338,16 @@ func (r *Rules) addRuleSetRepository(ruleset *api.RuleSet) (err error) {
 	}
 	var identity *api.Identity
 	if ruleset.Identity != nil {
-		identity, err = addon.Identity.Decrypted().Get(ruleset.Identity.ID)
+		identity, err = hubApi.Identity(ruleset.Identity.ID)
 		if err != nil {
 			return
 		}
 	}
-	rp, err := scm.New(
-		rootDir,
+	err = r.fetchRepository(
+		ruleset.ID,
 		*ruleset.Repository,
-		identity)
-	if err != nil {
-		return
-	}
-	err = rp.Fetch()
+		identity,
+		rootDir)
 	if err != nil {
 		return
 	}
@@ -310,19 +371,16 @@ func (r *Rules) addRepository() (err error) {
 	}
 	var identity *api.Identity
 	if r.Identity != nil {
-		identity, err = addon.Identity.Decrypted().Get(r.Identity.ID)
+		identity, err = hubApi.Identity(r.Identity.ID)
 		if err != nil {
 			return
 		}
 	}
-	rp, err := scm.New(
-		rootDir,
+	err = r.fetchRepository(
+		0,
 		*r.Repository,
-		identity)
-	if err != nil {
-		return
-	}
-	err = rp.Fetch()
+		identity,
+		rootDir)
 	if err != nil {
 		return
 	}
@@ -332,6 +390,145 @@ func (r *Rules) addRepository() (err error) {
 	return
 }
 
+// Provenance returns the provenance of the rules.
+func (r *Rules) Provenance() (p *builder.Provenance) {
+	if r.provenance.Empty() {
+		return
+	}
+	p = &r.provenance
+	return
+}
+
+// Fingerprint returns the digest of the resolved rules selection.
+// Includes the label selector, the repository commits and the file ids.
+// Empty when the rules are fetched from the bucket (content not versioned).
+func (r *Rules) Fingerprint() (d string) {
+	if r.provenance.Bucket != "" {
+		return
+	}
+	var parts []string
+	for _, repository := range r.provenance.Repositories {
+		parts = append(
+			parts,
+			fmt.Sprintf(
+				"repository:%d:%s:%s:%s:%s",
+				repository.RuleSet,
+				repository.URL,
+				repository.Branch,
+				repository.Path,
+				repository.Commit))
+	}
+	for _, f := range r.provenance.Files {
+		parts = append(
+			parts,
+			fmt.Sprintf("file:%d:%d", f.RuleSet, f.ID))
+	}
+	slices.Sort(parts)
+	parts = append(parts, "selector:"+r.getSelector())
+	h := sha256.New()
+	for _, part := range parts {
+		_, _ = h.Write([]byte(part))
+		_, _ = h.Write([]byte{0})
+	}
+	d = fmt.Sprintf("%x", h.Sum(nil))
+	return
+}
+
+// fetchRepository fetches the (cached) repository into the directory.
+// The repository is checked out at the pinned commit (when specified).
+// The cached clone is updated only when the pinned commit is not found.
+// The commit is recorded in the provenance.
+func (r *Rules) fetchRepository(
+	ruleSet uint,
+	repository api.Repository,
+	identity *api.Identity,
+	dir string) (err error) {
+	cache := RuleCache{}
+	pinned := r.Commits[repository.URL]
+	commit, err := cache.Repository(repository, identity, pinned != "", false, dir)
+	if err != nil {
+		return
+	}
+	if pinned != "" {
+		commit, err = r.pin(repository, dir, pinned)
+		if errors.Is(err, &PinError{}) && r.git(repository) {
+			// The cached clone may predate the commit.
+			// Updated (forced) and copied into the cleared directory.
+			err = os.RemoveAll(dir)
+			if err != nil {
+				return
+			}
+			_, err = cache.Repository(repository, identity, false, true, dir)
+			if err != nil {
+				return
+			}
+			commit, err = r.pin(repository, dir, pinned)
+		}
+		if err != nil {
+			return
+		}
+		addon.Activity(
+			"[RULESET] repository: %s pinned: %s",
+			repository.URL,
+			commit)
+	}
+	r.provenance.Repositories = append(
+		r.provenance.Repositories,
+		builder.RuleRepository{
+			RuleSet: ruleSet,
+			URL:     repository.URL,
+			Branch:  repository.Branch,
+			Path:    repository.Path,
+			Commit:  commit,
+			Pinned:  pinned != "",
+		})
+	return
+}
+
+// git returns true when the repository kind is git.
+func (r *Rules) git(repository api.Repository) (matched bool) {
+	matched = repository.Kind == "" || repository.Kind == "git"
+	return
+}
+
+// pin checks out the commit (detached) in the repository directory.
+// Returns the resolved commit.
+func (r *Rules) pin(repository api.Repository, dir, commit string) (head string, err error) {
+	if !r.git(repository) {
+		err = &PinError{
+			URL:    repository.URL,
+			Commit: commit,
+			Reason: "Only git is supported.",
+		}
+		return
+	}
+	git := func(args ...string) (output string, err error) {
+		cmd := command.New("/usr/bin/git")
+		cmd.Dir = dir
+		cmd.Env = append(
+			os.Environ(),
+			"GIT_TERMINAL_PROMPT=0")
+		cmd.Options.Add(args[0], args[1:]...)
+		err = cmd.Run()
+		if err != nil {
+			return
+		}
+		output = strings.TrimSpace(string(cmd.Output()))
+		return
+	}
+	_, err = git("-c", "advice.detachedHead=false", "checkout", "--detach", commit)
+	if err != nil {
+		err = &PinError{
+			URL:    repository.URL,
+			Commit: commit,
+			Reason: err.Error(),
+		}
+		return
+	}
+	head, err = git("rev-parse", "HEAD")
+	return
+}
+
 // addSelector adds label selector.
 func (r *Rules) getSelector() (selector string) {
 	ruleSelector := RuleSelector{
@@ -390,7 +587,7 @@ type Labels struct {
 
 // RuleSets returns a list of ruleSets matching the 'included' labels.
 func (r *Labels) RuleSets() (matched []api.RuleSet, err error) {
-	allRuleSets, err := addon.RuleSet.List()
+	allRuleSets, err := hubApi.RuleSets()
 	if err != nil {
 		return
 	}
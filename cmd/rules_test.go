@@ -0,0 +1,101 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/konveyor/tackle2-addon-analyzer/builder"
+	"github.com/konveyor/tackle2-hub/shared/api"
+	"github.com/onsi/gomega"
+)
+
+func TestRulesProvenance(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	tmp := useDirs(t)
+	// remote repository with 2 commits.
+	remote := path.Join(tmp, "remote")
+	err := os.MkdirAll(path.Join(remote, "rules"), 0755)
+	g.Expect(err).To(gomega.BeNil())
+	git := func(args ...string) (output string) {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = remote
+		cmd.Env = append(
+			os.Environ(),
+			"GIT_AUTHOR_NAME=test",
+			"GIT_AUTHOR_EMAIL=test@test",
+			"GIT_COMMITTER_NAME=test",
+			"GIT_COMMITTER_EMAIL=test@test")
+		b, gErr := cmd.CombinedOutput()
+		g.Expect(gErr).To(gomega.BeNil(), string(b))
+		output = strings.TrimSpace(string(b))
+		return
+	}
+	rulePath := path.Join(remote, "rules", "rules.yaml")
+	git("init", "-q")
+	err = os.WriteFile(rulePath, []byte("# v1\n"), 0644)
+	g.Expect(err).To(gomega.BeNil())
+	git("add", ".")
+	git("commit", "-q", "-m", "v1")
+	first := git("rev-parse", "HEAD")
+	err = os.WriteFile(rulePath, []byte("# v2\n"), 0644)
+	g.Expect(err).To(gomega.BeNil())
+	git("commit", "-q", "-a", "-m", "v2")
+	// file.
+	filePath := path.Join(tmp, "rule.yaml")
+	err = os.WriteFile(filePath, []byte("[]\n"), 0644)
+	g.Expect(err).To(gomega.BeNil())
+	url := "https://git.example.com/rules.git"
+	useFakeHub(t, &FakeHub{
+		Repositories: map[string]string{url: remote},
+		Files:        map[uint]string{10: filePath},
+	})
+	rules := Rules{
+		Repository: &api.Repository{URL: url, Path: "rules"},
+		Commits:    map[string]string{url: first},
+		ruleFiles:  []api.Ref{{ID: 10, Name: "rule.yaml"}},
+	}
+	g.Expect(rules.Provenance()).To(gomega.BeNil())
+	err = rules.addFiles()
+	g.Expect(err).To(gomega.BeNil())
+	err = rules.addRepository()
+	g.Expect(err).To(gomega.BeNil())
+	b, err := os.ReadFile(path.Join(RuleDir, "repository", "rules", "rules.yaml"))
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(string(b)).To(gomega.Equal("# v1\n"))
+	provenance := rules.Provenance()
+	g.Expect(provenance).NotTo(gomega.BeNil())
+	g.Expect(provenance.Files).To(gomega.Equal([]builder.RuleFile{{ID: 10, Name: "rule.yaml"}}))
+	g.Expect(len(provenance.Repositories)).To(gomega.Equal(1))
+	g.Expect(provenance.Repositories[0].Commit).To(gomega.Equal(first))
+	g.Expect(provenance.Repositories[0].Pinned).To(gomega.BeTrue())
+	// pinned commit not in the cached clone.
+	err = os.WriteFile(rulePath, []byte("# v3\n"), 0644)
+	g.Expect(err).To(gomega.BeNil())
+	git("commit", "-q", "-a", "-m", "v3")
+	third := git("rev-parse", "HEAD")
+	rules = Rules{
+		Repository: &api.Repository{URL: url, Path: "rules"},
+		Commits:    map[string]string{url: third},
+	}
+	err = os.RemoveAll(path.Join(RuleDir, "repository"))
+	g.Expect(err).To(gomega.BeNil())
+	err = rules.addRepository()
+	g.Expect(err).To(gomega.BeNil())
+	b, err = os.ReadFile(path.Join(RuleDir, "repository", "rules", "rules.yaml"))
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(string(b)).To(gomega.Equal("# v3\n"))
+	g.Expect(rules.Provenance().Repositories[0].Commit).To(gomega.Equal(third))
+	// not found.
+	rules = Rules{
+		Repository: &api.Repository{URL: url, Path: "rules"},
+		Commits:    map[string]string{url: "0000000000000000000000000000000000000000"},
+	}
+	err = os.RemoveAll(path.Join(RuleDir, "repository"))
+	g.Expect(err).To(gomega.BeNil())
+	err = rules.addRepository()
+	g.Expect(errors.Is(err, &PinError{})).To(gomega.BeTrue())
+}
@@ -26,19 +26,27 @@ func (r *Scope) With(p *api.ApScope) (err error) {
 
 // AddOptions adds analyzer options.
 func (r *Scope) ToOptions(mode Mode) (options []core.AnalyzerOption) {
+	selector := r.depLabelSelector(mode)
+	if selector != "" {
+		options = append(options, core.WithDepLabelSelector(selector))
+	}
+	selector = r.incidentSelector()
+	if selector != "" {
+		options = append(options, core.WithIncidentSelector(selector))
+	}
+	return
+}
+
+// depLabelSelector returns the dependency label selector.
+func (r *Scope) depLabelSelector(mode Mode) (selector string) {
 	// If withDeps is false, we are only every doing source analysis
 	// adding a dep label selector is strictly wrong in this situation
 	if mode.WithDeps {
 		// We want to filter out open source violations when we are not running
 		// with known libraries.
 		if !r.WithKnownLibs {
-			options = append(options, core.WithDepLabelSelector("!konveyor.io/dep-source=open-source"))
+			selector = "!konveyor.io/dep-source=open-source"
 		}
-
-	}
-	selector := r.incidentSelector()
-	if selector != "" {
-		options = append(options, core.WithIncidentSelector(selector))
 	}
 	return
 }
@@ -19,6 +19,8 @@ const (
 	Builtin = "builtin"
 	// BuiltinLocation The (code) Location passed to the provider.
 	BuiltinLocation = Builtin + ".location"
+	// BuiltinMode The analysis mode (full|source-only) of the provider.
+	BuiltinMode = Builtin + ".mode"
 )
 
 // Settings - provider settings file.
@@ -48,27 +50,84 @@ func (r *Settings) Read() (err error) {
 	return
 }
 
+// Build the provider settings.
+// The extensions are appended, the mode and proxy settings applied.
+func (r *Settings) Build(mode *Mode) (err error) {
+	err = r.AppendExtensions(mode)
+	if err != nil {
+		return
+	}
+	r.Mode(mode.AnalysisMode())
+	err = r.ProxySettings()
+	return
+}
+
 // AppendExtensions adds extension fragments.
+// The metadata is validated and all problems are reported
+// before any provider is started.
 func (r *Settings) AppendExtensions(mode *Mode) (err error) {
-	addon, err := addon.Addon(true)
+	definition, err := hubApi.Addon()
 	if err != nil {
 		return
 	}
-	for _, extension := range addon.Extensions {
-		var md *Metadata
-		md, err = r.metadata(&extension)
-		if r.hasProvider(&md.Provider) {
+	validator := Validator{}
+	for _, extension := range definition.Extensions {
+		md, mErr := r.metadata(&extension)
+		if mErr != nil {
+			validator.Error(extension.Name, "metadata not valid: %s", mErr.Error())
+			continue
+		}
+		if !validator.Metadata(extension.Name, md) {
 			continue
 		}
-		builtin := r.injectBuiltins(md, mode)
+		var unresolved []string
+		var initConfig []provider.InitConfig
+		injected := true
 		injector := ResourceInjector{}
-		injector.Use(builtin)
-		err = injector.Inject(md)
-		if err != nil {
-			return
+		for _, location := range mode.Locations() {
+			if !mode.incremental.Affected(r.location(md, mode, location)) {
+				continue
+			}
+			lmd := &Metadata{}
+			err = injector.object(injector.asMap(md), lmd)
+			if err != nil {
+				return
+			}
+			var builtin map[string]any
+			builtin, err = r.injectBuiltins(lmd, mode, location)
+			if err != nil {
+				return
+			}
+			injector.Use(builtin)
+			err = injector.Inject(lmd)
+			if err != nil {
+				if !r.notValid(err) {
+					return
+				}
+				validator.Error(extension.Name, "%s", err.Error())
+				err = nil
+				injected = false
+				break
+			}
+			unresolved = append(unresolved, injector.unresolved...)
+			initConfig = append(initConfig, lmd.Provider.InitConfig...)
+			md.Provider = lmd.Provider
 		}
+		if !injected {
+			continue
+		}
+		if len(initConfig) == 0 {
+			addon.Activity(
+				"[INCREMENTAL] %s: no files changed in locations.",
+				extension.Name)
+			continue
+		}
+		md.Provider.InitConfig = initConfig
+		validator.Unresolved(extension.Name, md, unresolved)
+		validator.Provider(extension.Name, &md.Provider)
 		r.Configs = append(r.Configs, md.Provider)
 	}
+	err = validator.Report()
 	return
 }
 
@@ -90,10 +149,11 @@ func (r *Settings) Write() (err error) {
 }
 
 // Mode update the mode on each provider.
+// The mode is set only when not overridden.
 func (r *Settings) Mode(mode provider.AnalysisMode) {
 	extensions := r.Configs[r.index:]
 	for i := range extensions {
-		p := extensions[i]
+		p := &extensions[i]
 		for i := range p.InitConfig {
 			init := &p.InitConfig[i]
 			if init.AnalysisMode == "" {
@@ -141,13 +201,52 @@ func (r *Settings) ProxySettings() (err error) {
 }
 
 // injectBuiltins injects `builtin` field values.
-func (r *Settings) injectBuiltins(md *Metadata, mode *Mode) (builtin map[string]any) {
+// The provider is initialized (once) for the location.
+// The metadata overrides are applied. Discovery always uses
+// source analysis mode (the mode override is ignored).
+func (r *Settings) injectBuiltins(md *Metadata, mode *Mode, location string) (builtin map[string]any, err error) {
 	builtin = make(map[string]any)
+	override := &md.Override
+	err = override.Validate()
+	if err != nil {
+		return
+	}
+	location = r.location(md, mode, location)
+	overridden := override.Mode != "" && !mode.Discovery
+	builtin[BuiltinMode] = string(provider.SourceOnlyAnalysisMode)
+	if mode.WithDeps && !mode.Discovery {
+		builtin[BuiltinMode] = string(provider.FullAnalysisMode)
+	}
+	if overridden {
+		builtin[BuiltinMode] = string(override.Mode)
+	}
 	list := md.Provider.InitConfig
 	for i := range list {
 		in := &list[i]
-		in.Location = mode.Location()
+		in.Location = location
+		if overridden {
+			in.AnalysisMode = override.Mode
+		}
 		builtin[BuiltinLocation] = in.Location
+		if mode.incremental.Enabled() {
+			if in.ProviderSpecificConfig == nil {
+				in.ProviderSpecificConfig = make(map[string]any)
+			}
+			var included []any
+			for _, p := range mode.incremental.Included(location) {
+				included = append(included, p)
+			}
+			in.ProviderSpecificConfig[provider.IncludedPathsConfigKey] = included
+		}
+	}
+	return
+}
+
+// location returns the location with the (metadata) path override applied.
+func (r *Settings) location(md *Metadata, mode *Mode, location string) (p string) {
+	p = location
+	if md.Override.Path != "" && !mode.Binary {
+		p = path.Join(location, path.Clean("/"+md.Override.Path))
 	}
 	return
 }
@@ -157,18 +256,18 @@ func (r *Settings) getProxy(kind string) (url string, excluded []string, err err
 	var p *api.Proxy
 	var id *api.Identity
 	var user, password string
-	p, err = addon.Proxy.Find(kind)
+	p, err = hubApi.Proxy(kind)
 	if err != nil {
 		if errors.Is(err, &hub.NotFound{}) {
 			err = nil
-			return
 		}
+		return
 	}
-	if p.Host == "" {
+	if p == nil || p.Host == "" {
 		return
 	}
 	if p.Identity != nil {
-		id, err = addon.Identity.Decrypted().Get(p.Identity.ID)
+		id, err = hubApi.Identity(p.Identity.ID)
 		if err == nil {
 			user = id.User
 			password = id.Password
@@ -193,14 +292,14 @@ func (r *Settings) path() (p string) {
 	return path.Join(OptDir, "settings.yaml")
 }
 
-// hasProvider returns true when the provider found.
-func (r *Settings) hasProvider(p *provider.Config) (found bool) {
-	for i := range r.Configs {
-		if r.Configs[i].Name == p.Name {
-			found = true
-			break
-		}
-	}
+// notValid returns true when the (injection) error
+// is caused by metadata not valid.
+func (r *Settings) notValid(err error) (b bool) {
+	b = errors.Is(err, &ExpressionError{}) ||
+		errors.Is(err, &TypeError{}) ||
+		errors.Is(err, &KeyConflictError{}) ||
+		errors.Is(err, &FieldNotMatched{}) ||
+		errors.Is(err, &SelectorNotSupported{})
 	return
 }
 
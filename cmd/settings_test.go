@@ -0,0 +1,67 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/konveyor/analyzer-lsp/provider"
+	"github.com/konveyor/tackle2-hub/shared/api"
+	"github.com/onsi/gomega"
+)
+
+func TestProviderOverride(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	fake := &FakeHub{
+		App: api.Application{
+			Resource: api.Resource{ID: 1},
+		},
+		AddonDef: api.Addon{
+			Extensions: []api.Extension{
+				{
+					Name: "java",
+					Metadata: api.Map{
+						"provider": api.Map{
+							"name": "java",
+							"initConfig": []api.Map{
+								{"location": "$(builtin.location)"},
+							},
+						},
+					},
+				},
+				{
+					Name: "builtin",
+					Metadata: api.Map{
+						"override": api.Map{
+							"mode": "source-only",
+							"path": "src/main",
+						},
+						"provider": api.Map{
+							"name": "builtin",
+							"initConfig": []api.Map{
+								{"location": "$(builtin.location)"},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	useFakeHub(t, fake)
+	mode := Mode{WithDeps: true}
+	mode.path.appDir = "/app"
+	settings := Settings{}
+	err := settings.AppendExtensions(&mode)
+	g.Expect(err).To(gomega.BeNil())
+	settings.Mode(mode.AnalysisMode())
+	g.Expect(len(settings.Configs)).To(gomega.Equal(2))
+	java := settings.Configs[0].InitConfig[0]
+	g.Expect(java.Location).To(gomega.Equal("/app"))
+	g.Expect(java.AnalysisMode).To(gomega.Equal(provider.FullAnalysisMode))
+	builtin := settings.Configs[1].InitConfig[0]
+	g.Expect(builtin.Location).To(gomega.Equal("/app/src/main"))
+	g.Expect(builtin.AnalysisMode).To(gomega.Equal(provider.SourceOnlyAnalysisMode))
+	// not valid.
+	fake.AddonDef.Extensions[1].Metadata.(api.Map)["override"] = api.Map{"mode": "partial"}
+	settings = Settings{}
+	err = settings.AppendExtensions(&mode)
+	g.Expect(err).ToNot(gomega.BeNil())
+}
@@ -1,18 +1,41 @@
 package main
 
 import (
+	"fmt"
+	"os"
+	"path"
 	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 
+	"github.com/konveyor/tackle2-addon-analyzer/builder"
 	"github.com/konveyor/tackle2-hub/shared/addon/command"
 	"github.com/konveyor/tackle2-hub/shared/api"
+	"gopkg.in/yaml.v2"
 )
 
-var TagExp = regexp.MustCompile("(.+)(=)(.+)")
+// TagExp tag declaration.
+//
+//	[source::]category[/subcategory]=name[;color=#rrggbb][;rank=n]
+var TagExp = regexp.MustCompile(`^(?:([^:=]+)::)?([^=]+)=([^;]+)((?:;[^;]*)*)$`)
+
+// TagWorkers the maximum number of tags (and categories)
+// created concurrently.
+var TagWorkers = 4
+
+// ColorExp tag category color.
+var ColorExp = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
 
 // Tagger tags an application.
 type Tagger struct {
 	Enabled bool   `json:"enabled"`
 	Source  string `json:"source"`
+	// Mapping tag mapping (and filtering).
+	Mapping TagMapping `json:"mapping"`
+	// tagged the (mapped) tags associated.
+	tagged map[TagDecl]bool
 }
 
 // AddOptions adds analyzer options.
@@ -21,16 +44,26 @@ func (r *Tagger) AddOptions(options *command.Options) (err error) {
 }
 
 // Update updates application tags.
+//   - Parses tag declarations.
+//   - Applies the mapping.
 //   - Ensures categories exist.
 //   - Endures tags exist.
 //   - Replaces associated tags (by source).
 func (r *Tagger) Update(appID uint, tags []string) (err error) {
 	addon.Activity("[TAG] Tagging Application %d.", appID)
-	catMap, err := r.ensureCategories(tags)
+	decls, err := r.Mapping.Apply(r.parse(tags))
 	if err != nil {
 		return
 	}
-	wanted, err := r.ensureTags(catMap, tags)
+	r.tagged = make(map[TagDecl]bool)
+	for _, decl := range decls {
+		r.tagged[decl.Ref()] = true
+	}
+	catMap, err := r.ensureCategories(decls)
+	if err != nil {
+		return
+	}
+	wanted, err := r.ensureTags(catMap, decls)
 	if err != nil {
 		return
 	}
@@ -41,78 +74,426 @@ func (r *Tagger) Update(appID uint, tags []string) (err error) {
 	return
 }
 
+// parse tag declarations.
+// Invalid declarations are dropped and reported.
+func (r *Tagger) parse(tags []string) (decls []TagDecl) {
+	history := make(map[string]bool)
+	for _, s := range tags {
+		if history[s] {
+			continue
+		}
+		history[s] = true
+		decl := TagDecl{}
+		err := decl.Parse(s)
+		if err != nil {
+			addon.Error(api.TaskError{
+				Severity:    SeverityWarning,
+				Description: "[TAG] dropped: " + err.Error(),
+			})
+			continue
+		}
+		decls = append(decls, decl)
+	}
+	return
+}
+
 // ensureCategories ensures categories exist.
+// The existing categories are listed once and only the
+// missing categories are created (concurrently).
+// The color and rank are updated as needed.
 // Returns the map of category names to IDs.
-func (r *Tagger) ensureCategories(tags []string) (catMap map[string]uint, err error) {
+func (r *Tagger) ensureCategories(decls []TagDecl) (catMap map[string]uint, err error) {
 	catMap = map[string]uint{}
-	wanted := []api.TagCategory{}
-	for _, s := range tags {
-		m := TagExp.FindStringSubmatch(s)
-		if len(m) == 4 {
-			catMap[m[1]] = 0
+	wanted := map[string]*api.TagCategory{}
+	for _, decl := range decls {
+		cat, found := wanted[decl.Category]
+		if !found {
+			cat = &api.TagCategory{Name: decl.Category}
+			wanted[decl.Category] = cat
+		}
+		if cat.Color == "" {
+			cat.Color = decl.Color
+		}
+		if cat.Rank == 0 {
+			cat.Rank = decl.Rank
 		}
 	}
-	for name := range catMap {
-		wanted = append(
-			wanted,
-			api.TagCategory{Name: name})
+	if len(wanted) == 0 {
+		return
 	}
-	for _, cat := range wanted {
-		err = addon.TagCategory.Ensure(&cat)
-		if err != nil {
-			return
+	list, err := hubApi.TagCategoryList()
+	if err != nil {
+		return
+	}
+	existing := map[string]api.TagCategory{}
+	for _, cat := range list {
+		existing[cat.Name] = cat
+	}
+	names := make([]string, 0, len(wanted))
+	for name := range wanted {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	missing := []api.TagCategory{}
+	for _, name := range names {
+		want := wanted[name]
+		cat, found := existing[name]
+		if !found {
+			missing = append(missing, *want)
+			continue
+		}
+		catMap[cat.Name] = cat.ID
+		updated := false
+		if want.Color != "" && want.Color != cat.Color {
+			cat.Color = want.Color
+			updated = true
+		}
+		if want.Rank != 0 && want.Rank != cat.Rank {
+			cat.Rank = want.Rank
+			updated = true
 		}
+		if updated {
+			err = hubApi.TagCategoryUpdate(&cat)
+			if err != nil {
+				return
+			}
+		}
+	}
+	err = r.parallel(
+		len(missing),
+		func(i int) (err error) {
+			err = hubApi.TagCategoryEnsure(&missing[i])
+			return
+		})
+	if err != nil {
+		return
+	}
+	for _, cat := range missing {
 		catMap[cat.Name] = cat.ID
 	}
+	addon.Activity(
+		"[TAG] categories: created=%d reused=%d.",
+		len(missing),
+		len(wanted)-len(missing))
 	return
 }
 
 // ensureTags ensures tags exist.
-// Returns the wanted tag IDs.
-func (r *Tagger) ensureTags(catMap map[string]uint, tags []string) (tagIds []uint, err error) {
-	mp := map[TagRef]int{}
-	wanted := []api.Tag{}
-	for _, s := range tags {
-		m := TagExp.FindStringSubmatch(s)
-		if len(m) == 4 {
-			ref := TagRef{
-				Category: m[1],
-				Name:     m[3],
+// The existing tags are listed once and only the
+// missing tags are created (concurrently).
+// Returns the wanted tag IDs by source.
+func (r *Tagger) ensureTags(catMap map[string]uint, decls []TagDecl) (tagIds map[string][]uint, err error) {
+	tagIds = map[string][]uint{r.Source: nil}
+	if len(decls) == 0 {
+		return
+	}
+	list, err := hubApi.TagList()
+	if err != nil {
+		return
+	}
+	existing := map[tagKey]uint{}
+	for _, tag := range list {
+		key := tagKey{
+			category: tag.Category.ID,
+			name:     tag.Name,
+		}
+		existing[key] = tag.ID
+	}
+	wanted := map[tagKey][]string{}
+	missing := []tagKey{}
+	history := map[TagDecl]bool{}
+	for _, decl := range decls {
+		ref := decl.Ref()
+		if history[ref] {
+			continue
+		}
+		history[ref] = true
+		key := tagKey{
+			category: catMap[decl.Category],
+			name:     decl.Name,
+		}
+		if _, found := wanted[key]; !found {
+			if _, found = existing[key]; !found {
+				missing = append(missing, key)
 			}
-			mp[ref] = 0
 		}
+		wanted[key] = append(wanted[key], r.source(decl))
 	}
-	for ref := range mp {
-		catRef := api.Ref{
-			ID: catMap[ref.Category],
+	created := make([]api.Tag, len(missing))
+	for i, key := range missing {
+		created[i] = api.Tag{
+			Name:     key.name,
+			Category: api.Ref{ID: key.category},
 		}
-		wanted = append(
-			wanted,
-			api.Tag{
-				Name:     ref.Name,
-				Category: catRef,
-			})
 	}
-	for _, tag := range wanted {
-		err = addon.Tag.Ensure(&tag)
+	err = r.parallel(
+		len(created),
+		func(i int) (err error) {
+			err = hubApi.TagEnsure(&created[i])
+			return
+		})
+	if err != nil {
+		return
+	}
+	for i := range missing {
+		existing[missing[i]] = created[i].ID
+	}
+	keys := make([]tagKey, 0, len(wanted))
+	for key := range wanted {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].category != keys[j].category {
+			return keys[i].category < keys[j].category
+		}
+		return keys[i].name < keys[j].name
+	})
+	for _, key := range keys {
+		for _, source := range wanted[key] {
+			tagIds[source] = append(tagIds[source], existing[key])
+		}
+	}
+	addon.Activity(
+		"[TAG] tags: created=%d reused=%d.",
+		len(missing),
+		len(wanted)-len(missing))
+	return
+}
+
+// parallel calls the function for each index (0-n) with
+// concurrency bounded by TagWorkers.
+// Returns the first error.
+func (r *Tagger) parallel(n int, fn func(i int) error) (err error) {
+	errs := make([]error, n)
+	bounded := make(chan struct{}, max(TagWorkers, 1))
+	wg := sync.WaitGroup{}
+	for i := 0; i < n; i++ {
+		bounded <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-bounded
+				wg.Done()
+			}()
+			errs[i] = fn(i)
+		}()
+	}
+	wg.Wait()
+	for _, err = range errs {
+		if err != nil {
+			break
+		}
+	}
+	return
+}
+
+// ensureAssociated ensure wanted tags are associated (by source).
+// The namespaced sources already associated but no longer wanted
+// are cleared.
+func (r *Tagger) ensureAssociated(appID uint, wanted map[string][]uint) (err error) {
+	err = r.ensureStale(appID, wanted)
+	if err != nil {
+		return
+	}
+	sources := make([]string, 0, len(wanted))
+	for source := range wanted {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+	for _, source := range sources {
+		err = hubApi.AppTagReplace(appID, source, wanted[source])
 		if err != nil {
 			return
 		}
-		tagIds = append(tagIds, tag.ID)
 	}
 	return
 }
 
-// ensureAssociated ensure wanted tags are associated.
-func (r *Tagger) ensureAssociated(appID uint, wanted []uint) (err error) {
-	err = addon.Application.Select(appID).
-		Tag.Source(r.Source).
-		Replace(wanted)
+// ensureStale adds the namespaced (r.Source:*) sources already
+// associated with the application but not wanted so that the
+// stale tags are cleared.
+func (r *Tagger) ensureStale(appID uint, wanted map[string][]uint) (err error) {
+	associated, err := hubApi.AppTagList(appID)
+	if err != nil {
+		return
+	}
+	for _, ref := range associated {
+		if !strings.HasPrefix(ref.Source, r.Source+":") {
+			continue
+		}
+		if _, found := wanted[ref.Source]; !found {
+			wanted[ref.Source] = nil
+		}
+	}
+	return
+}
+
+// Attach the tag evidence report.
+// The evidence (incidents) for each tag is reported with the
+// (namespaced) source the tag is associated with.
+func (r *Tagger) Attach(evidence map[string][]builder.TagEvidence) (err error) {
+	mp := map[TagDecl]*TagReport{}
+	for s, list := range evidence {
+		decl := TagDecl{}
+		pErr := decl.Parse(s)
+		if pErr != nil {
+			continue
+		}
+		decl = r.Mapping.Map(decl)
+		ref := decl.Ref()
+		if !r.tagged[ref] {
+			continue
+		}
+		report, found := mp[ref]
+		if !found {
+			report = &TagReport{
+				Source: r.source(decl),
+				Tag:    decl.Category + "=" + decl.Name,
+			}
+			mp[ref] = report
+		}
+		report.Evidence = append(report.Evidence, list...)
+	}
+	reports := make([]TagReport, 0, len(mp))
+	for _, report := range mp {
+		reports = append(reports, *report)
+	}
+	sort.Slice(reports, func(i, j int) bool {
+		if reports[i].Source != reports[j].Source {
+			return reports[i].Source < reports[j].Source
+		}
+		return reports[i].Tag < reports[j].Tag
+	})
+	b, err := yaml.Marshal(reports)
+	if err != nil {
+		return
+	}
+	p := path.Join(Dir, "tag-evidence.yaml")
+	err = os.WriteFile(p, b, 0644)
+	if err != nil {
+		return
+	}
+	f, err := hubApi.FilePost(p)
+	if err != nil {
+		return
+	}
+	addon.Attach(f)
+	addon.Activity("[TAG] evidence attached for %d tags.", len(reports))
+	return
+}
+
+// source returns the (namespaced) source for the declaration.
+func (r *Tagger) source(decl TagDecl) (source string) {
+	source = r.Source
+	if decl.Source != "" {
+		source = r.Source + ":" + decl.Source
+	}
 	return
 }
 
-// TagRef -
-type TagRef struct {
+// tagKey tag key (category ID, name).
+type tagKey struct {
+	category uint
+	name     string
+}
+
+// TagReport tag evidence report.
+type TagReport struct {
+	Source   string                `yaml:"source"`
+	Tag      string                `yaml:"tag"`
+	Evidence []builder.TagEvidence `yaml:"evidence"`
+}
+
+// TagDecl tag declaration.
+type TagDecl struct {
+	// Source namespace.
+	Source string `json:"source,omitempty"`
+	// Category (path) name.
 	Category string `json:"category"`
-	Name     string `json:"name"`
+	// Name tag name.
+	Name string `json:"name"`
+	// Color category color.
+	Color string `json:"color,omitempty"`
+	// Rank category rank.
+	Rank uint `json:"rank,omitempty"`
+}
+
+// Parse the tag declaration.
+// Category path segments are trimmed and joined by (/).
+func (r *TagDecl) Parse(s string) (err error) {
+	m := TagExp.FindStringSubmatch(s)
+	if len(m) != 5 {
+		err = &TagError{Tag: s, Reason: "expected: category=name"}
+		return
+	}
+	r.Source = strings.TrimSpace(m[1])
+	r.Name = strings.TrimSpace(m[3])
+	var path []string
+	for _, part := range strings.Split(m[2], "/") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			err = &TagError{Tag: s, Reason: "empty category"}
+			return
+		}
+		path = append(path, part)
+	}
+	r.Category = strings.Join(path, "/")
+	if r.Name == "" {
+		err = &TagError{Tag: s, Reason: "empty name"}
+		return
+	}
+	for _, attr := range strings.Split(m[4], ";") {
+		attr = strings.TrimSpace(attr)
+		if attr == "" {
+			continue
+		}
+		k, v, _ := strings.Cut(attr, "=")
+		k = strings.TrimSpace(k)
+		v = strings.TrimSpace(v)
+		switch k {
+		case "color":
+			if !ColorExp.MatchString(v) {
+				err = &TagError{Tag: s, Reason: "color must be: #rrggbb"}
+				return
+			}
+			r.Color = v
+		case "rank":
+			n, pErr := strconv.ParseUint(v, 10, 32)
+			if pErr != nil {
+				err = &TagError{Tag: s, Reason: "rank must be: unsigned integer"}
+				return
+			}
+			r.Rank = uint(n)
+		default:
+			err = &TagError{Tag: s, Reason: "attribute not supported: " + k}
+			return
+		}
+	}
+	return
+}
+
+// Ref returns the declaration without attributes.
+func (r *TagDecl) Ref() (ref TagDecl) {
+	ref = TagDecl{
+		Source:   r.Source,
+		Category: r.Category,
+		Name:     r.Name,
+	}
+	return
+}
+
+// TagError reports an invalid tag declaration.
+type TagError struct {
+	Tag    string
+	Reason string
+}
+
+func (e *TagError) Error() (s string) {
+	s = fmt.Sprintf("tag '%s' not valid: %s", e.Tag, e.Reason)
+	return
+}
+
+func (e *TagError) Is(err error) (matched bool) {
+	_, matched = err.(*TagError)
+	return
 }
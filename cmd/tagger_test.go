@@ -0,0 +1,96 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/konveyor/tackle2-hub/shared/api"
+	"github.com/onsi/gomega"
+)
+
+func TestTagger(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	fake := &FakeHub{
+		Categories: []api.TagCategory{
+			{Resource: api.Resource{ID: 1}, Name: "Language", Color: "#000000"},
+		},
+		nextId: 100,
+	}
+	useFakeHub(t, fake)
+	// parse.
+	decl := TagDecl{}
+	err := decl.Parse("discovery:: Framework / Web =Spring MVC;color=#ff0000;rank=2")
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(decl).To(gomega.Equal(TagDecl{
+		Source:   "discovery",
+		Category: "Framework/Web",
+		Name:     "Spring MVC",
+		Color:    "#ff0000",
+		Rank:     2,
+	}))
+	for _, s := range []string{"Java", "=Java", "Language/=Java", "Language=Java;color=red", "Language=Java;size=2"} {
+		err = decl.Parse(s)
+		g.Expect(errors.Is(err, &TagError{})).To(gomega.BeTrue(), s)
+	}
+	// update.
+	tagger := Tagger{Source: Source}
+	err = tagger.Update(
+		1,
+		[]string{
+			"Language=Java;color=#00ff00",
+			"Language=Java",
+			"Framework/Web=Spring MVC",
+			"discovery::Build=Maven",
+			"Java",
+		})
+	g.Expect(err).To(gomega.BeNil())
+	cat, found, _ := fake.TagCategory("Language")
+	g.Expect(found).To(gomega.BeTrue())
+	g.Expect(cat.Color).To(gomega.Equal("#00ff00"))
+	_, found, _ = fake.TagCategory("Framework/Web")
+	g.Expect(found).To(gomega.BeTrue())
+	g.Expect(fake.TagNames(Source)).To(gomega.ConsistOf("Language=Java", "Framework/Web=Spring MVC"))
+	g.Expect(fake.TagNames(Source + ":discovery")).To(gomega.ConsistOf("Build=Maven"))
+	// stale namespaced source cleared.
+	fake.AppTagIds["other"] = []uint{1}
+	err = tagger.Update(1, []string{"Language=Java"})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(fake.TagNames(Source)).To(gomega.ConsistOf("Language=Java"))
+	g.Expect(fake.AppTagIds).To(gomega.HaveKey(Source + ":discovery"))
+	g.Expect(fake.AppTagIds[Source+":discovery"]).To(gomega.BeEmpty())
+	g.Expect(fake.AppTagIds["other"]).To(gomega.Equal([]uint{1}))
+}
+
+func TestTaggerBatch(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	fake := &FakeHub{
+		Categories: []api.TagCategory{
+			{Resource: api.Resource{ID: 1}, Name: "Language"},
+		},
+		Tags: []api.Tag{
+			{Resource: api.Resource{ID: 2}, Name: "Java", Category: api.Ref{ID: 1}},
+		},
+		nextId: 100,
+	}
+	useFakeHub(t, fake)
+	var tags []string
+	for i := 0; i < 20; i++ {
+		tags = append(tags, fmt.Sprintf("Framework=F%02d", i))
+	}
+	tags = append(tags, "Language=Java", "Language=Go")
+	tagger := Tagger{Source: Source}
+	err := tagger.Update(1, tags)
+	g.Expect(err).To(gomega.BeNil())
+	// created: 1 category, 21 tags.
+	g.Expect(fake.nextId).To(gomega.Equal(uint(122)))
+	g.Expect(len(fake.Categories)).To(gomega.Equal(2))
+	g.Expect(len(fake.Tags)).To(gomega.Equal(22))
+	g.Expect(fake.AppTagIds[Source]).To(gomega.ContainElement(uint(2)))
+	g.Expect(len(fake.AppTagIds[Source])).To(gomega.Equal(22))
+	// reused.
+	err = tagger.Update(1, tags)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(fake.nextId).To(gomega.Equal(uint(122)))
+	g.Expect(len(fake.AppTagIds[Source])).To(gomega.Equal(22))
+}
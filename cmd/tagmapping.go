@@ -0,0 +1,116 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// TagMapping curates the tags reported by the rules.
+//   - Tags are renamed (merged).
+//   - Categories are renamed (merged).
+//   - Categories are filtered (included/excluded).
+//   - Tags are capped (by category).
+type TagMapping struct {
+	// Tags renamed: category=name => category=name.
+	Tags map[string]string `json:"tags"`
+	// Categories renamed: category => category.
+	Categories map[string]string `json:"categories"`
+	// Included categories. Empty = all.
+	Included []string `json:"included"`
+	// Excluded categories.
+	Excluded []string `json:"excluded"`
+	// MaxTags the maximum number of tags by category. 0 = unlimited.
+	MaxTags int `json:"maxTags"`
+}
+
+// Apply the mapping to the tag declarations.
+// The tags filtered (or capped) are reported.
+func (r *TagMapping) Apply(decls []TagDecl) (mapped []TagDecl, err error) {
+	for _, to := range r.Tags {
+		decl := TagDecl{}
+		err = decl.Parse(to)
+		if err != nil {
+			return
+		}
+	}
+	history := map[string]bool{}
+	byCategory := map[string][]string{}
+	for _, decl := range decls {
+		decl = r.Map(decl)
+		key := decl.Category + "=" + decl.Name
+		if !r.included(decl.Category) {
+			if !history[key] {
+				history[key] = true
+				addon.Activity("[TAG] filtered: %s", key)
+			}
+			continue
+		}
+		mapped = append(mapped, decl)
+		if !history[key] {
+			history[key] = true
+			byCategory[decl.Category] = append(byCategory[decl.Category], decl.Name)
+		}
+	}
+	if r.MaxTags < 1 {
+		return
+	}
+	categories := make([]string, 0, len(byCategory))
+	for category := range byCategory {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+	capped := map[string]bool{}
+	for _, category := range categories {
+		names := byCategory[category]
+		sort.Strings(names)
+		for _, name := range names[min(r.MaxTags, len(names)):] {
+			key := category + "=" + name
+			capped[key] = true
+			addon.Activity("[TAG] capped: %s", key)
+		}
+	}
+	kept := []TagDecl{}
+	for _, decl := range mapped {
+		if !capped[decl.Category+"="+decl.Name] {
+			kept = append(kept, decl)
+		}
+	}
+	mapped = kept
+	return
+}
+
+// Map returns the mapped declaration (without filtering).
+func (r *TagMapping) Map(decl TagDecl) (mapped TagDecl) {
+	mapped = decl
+	if to, found := r.Tags[decl.Category+"="+decl.Name]; found {
+		toDecl := TagDecl{}
+		err := toDecl.Parse(to)
+		if err == nil {
+			mapped.Category = toDecl.Category
+			mapped.Name = toDecl.Name
+		}
+	}
+	if to, found := r.Categories[mapped.Category]; found {
+		mapped.Category = to
+	}
+	return
+}
+
+// included returns true when the category is included and not excluded.
+// A category matches the name or (hierarchical) name/ prefix.
+func (r *TagMapping) included(category string) (b bool) {
+	match := func(names []string) (matched bool) {
+		for _, name := range names {
+			if category == name || strings.HasPrefix(category, name+"/") {
+				matched = true
+				break
+			}
+		}
+		return
+	}
+	b = len(r.Included) == 0 || match(r.Included)
+	if b {
+		b = !match(r.Excluded)
+	}
+	return
+}
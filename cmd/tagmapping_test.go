@@ -0,0 +1,68 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/konveyor/tackle2-addon-analyzer/builder"
+	"github.com/onsi/gomega"
+	"gopkg.in/yaml.v2"
+)
+
+func TestTagMapping(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	useDirs(t)
+	fake := &FakeHub{nextId: 100}
+	useFakeHub(t, fake)
+	tagger := Tagger{
+		Source: Source,
+		Mapping: TagMapping{
+			Tags: map[string]string{
+				"Java EE=Jakarta EE": "Java EE=Java EE",
+			},
+			Categories: map[string]string{
+				"Jakarta EE": "Java EE",
+			},
+			Excluded: []string{"Other"},
+			MaxTags:  2,
+		},
+	}
+	err := tagger.Update(
+		1,
+		[]string{
+			"Java EE=Jakarta EE",
+			"Java EE=Java EE",
+			"Jakarta EE=JPA",
+			"Language=Java",
+			"Language=Kotlin",
+			"Language=Scala",
+			"Other=Thing",
+			"Other/Sub=Thing",
+		})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(fake.TagNames(Source)).To(gomega.ConsistOf(
+		"Java EE=Java EE",
+		"Java EE=JPA",
+		"Language=Java",
+		"Language=Kotlin"))
+	_, found, _ := fake.TagCategory("Jakarta EE")
+	g.Expect(found).To(gomega.BeFalse())
+	_, found, _ = fake.TagCategory("Other")
+	g.Expect(found).To(gomega.BeFalse())
+	// evidence mapped.
+	err = tagger.Attach(
+		map[string][]builder.TagEvidence{
+			"Jakarta EE=JPA": {{RuleSet: "a", Rule: "r1", File: "/f"}},
+			"Language=Scala": {{RuleSet: "a", Rule: "r2", File: "/f"}},
+		})
+	g.Expect(err).To(gomega.BeNil())
+	var reports []TagReport
+	err = yaml.Unmarshal([]byte(fake.Posted["tag-evidence.yaml"]), &reports)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(reports)).To(gomega.Equal(1))
+	g.Expect(reports[0].Tag).To(gomega.Equal("Java EE=JPA"))
+	// invalid mapping.
+	tagger.Mapping.Tags = map[string]string{"A=B": "C"}
+	err = tagger.Update(1, []string{"A=B"})
+	g.Expect(errors.Is(err, &TagError{})).To(gomega.BeTrue())
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/konveyor/analyzer-lsp/provider"
+	"github.com/konveyor/tackle2-hub/shared/api"
+)
+
+// Problem severity.
+const (
+	SeverityError   = "Error"
+	SeverityWarning = "Warning"
+)
+
+// Selector kinds supported by the resource injector.
+var SelectorKinds = map[string]bool{
+	"identity":    true,
+	"setting":     true,
+	"application": true,
+	"tag":         true,
+	"proxy":       true,
+	"file":        true,
+}
+
+// FieldTypes supported field types.
+var FieldTypes = map[string]bool{
+	"":        true,
+	"string":  true,
+	"integer": true,
+	"boolean": true,
+}
+
+// MetadataError reports extension metadata not valid.
+type MetadataError struct {
+	Problems []api.TaskError
+}
+
+func (e *MetadataError) Error() (s string) {
+	return fmt.Sprintf(
+		"Extension metadata not valid: %d problems.",
+		len(e.Problems))
+}
+
+func (e *MetadataError) Is(err error) (matched bool) {
+	var inst *MetadataError
+	matched = errors.As(err, &inst)
+	return
+}
+
+// Validator validates extension metadata.
+type Validator struct {
+	Problems []api.TaskError
+	// providers name => extension.
+	providers map[string]string
+}
+
+// Metadata validates the metadata (before injection).
+// Returns false when not valid or the provider is defined by
+// another extension. The first extension is used.
+func (r *Validator) Metadata(extension string, md *Metadata) (valid bool) {
+	n := r.errors()
+	if md.Provider.Name == "" {
+		r.Error(extension, "provider.name must be specified.")
+	} else {
+		if r.providers == nil {
+			r.providers = make(map[string]string)
+		}
+		other, found := r.providers[md.Provider.Name]
+		if found {
+			r.Warning(
+				extension,
+				"provider.name=%s duplicates extension: %s. Ignored.",
+				md.Provider.Name,
+				other)
+			return
+		}
+		r.providers[md.Provider.Name] = extension
+	}
+	err := md.Override.Validate()
+	if err != nil {
+		r.Error(extension, "%s", err.Error())
+	}
+	keys := make(map[string]bool)
+	for i, resource := range md.Resources {
+		parsed := ParsedSelector{}
+		parsed.With(resource.Selector)
+		kind := strings.ToLower(parsed.kind)
+		switch {
+		case resource.Selector == "":
+			r.Error(extension, "resources[%d].selector must be specified.", i)
+		case !SelectorKinds[kind]:
+			r.Error(
+				extension,
+				"resources[%d].selector=%s kind not-supported.",
+				i,
+				resource.Selector)
+		case kind != "application" && parsed.value == "":
+			r.Error(
+				extension,
+				"resources[%d].selector=%s value must be specified.",
+				i,
+				resource.Selector)
+		case kind == "file" && !r.fileId(&parsed):
+			r.Error(
+				extension,
+				"resources[%d].selector=%s not-supported. expected: file:id=<id>",
+				i,
+				resource.Selector)
+		}
+		for j, f := range resource.Fields {
+			if f.Name == "" {
+				r.Error(extension, "resources[%d].fields[%d].name must be specified.", i, j)
+			}
+			if f.Key == "" {
+				r.Error(extension, "resources[%d].fields[%d].key must be specified.", i, j)
+			} else if keys[f.Key] {
+				r.Error(extension, "resources[%d].fields[%d].key=%s duplicated.", i, j, f.Key)
+			}
+			keys[f.Key] = true
+			if !FieldTypes[strings.ToLower(f.Type)] {
+				r.Error(
+					extension,
+					"resources[%d].fields[%d].type=%s not-supported. expected: string|integer|boolean",
+					i,
+					j,
+					f.Type)
+			}
+		}
+	}
+	valid = r.errors() == n
+	return
+}
+
+// Provider validates the provider (after injection).
+func (r *Validator) Provider(extension string, p *provider.Config) {
+	if p.Address == "" {
+		return
+	}
+	_, port, err := net.SplitHostPort(p.Address)
+	if err != nil {
+		r.Error(extension, "provider.address=%s not valid: %s", p.Address, err.Error())
+		return
+	}
+	n, err := strconv.Atoi(port)
+	if err != nil || n < 1 || n > 65535 {
+		r.Error(extension, "provider.address=%s port not valid.", p.Address)
+	}
+}
+
+// Unresolved reports variables not resolved by injection.
+// The variables are injected as empty. Keys declared by the resource
+// fields are not reported; the resource is optional (not selected).
+func (r *Validator) Unresolved(extension string, md *Metadata, keys []string) {
+	seen := make(map[string]bool)
+	for _, resource := range md.Resources {
+		for _, f := range resource.Fields {
+			seen[f.Key] = true
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		r.Warning(
+			extension,
+			"$(%s) not resolved. Use $(%s:-default) or $(%s:?message).",
+			key,
+			key,
+			key)
+	}
+}
+
+// Error adds an error.
+func (r *Validator) Error(extension, description string, v ...any) {
+	r.add(SeverityError, extension, description, v...)
+}
+
+// Warning adds a warning.
+func (r *Validator) Warning(extension, description string, v ...any) {
+	r.add(SeverityWarning, extension, description, v...)
+}
+
+// Report the problems.
+// Returns MetadataError when errors found.
+func (r *Validator) Report() (err error) {
+	if len(r.Problems) == 0 {
+		return
+	}
+	addon.Error(r.Problems...)
+	if r.errors() > 0 {
+		err = &MetadataError{Problems: r.Problems}
+	}
+	return
+}
+
+// add a problem.
+func (r *Validator) add(severity, extension, description string, v ...any) {
+	r.Problems = append(
+		r.Problems,
+		api.TaskError{
+			Severity: severity,
+			Description: fmt.Sprintf(
+				"[EXTENSION] %s: %s",
+				extension,
+				fmt.Sprintf(description, v...)),
+		})
+}
+
+// errors returns the number of errors.
+func (r *Validator) errors() (n int) {
+	for _, p := range r.Problems {
+		if p.Severity == SeverityError {
+			n++
+		}
+	}
+	return
+}
+
+// fileId returns true when the file selector references a file by id.
+func (r *Validator) fileId(parsed *ParsedSelector) (valid bool) {
+	_, err := strconv.ParseUint(parsed.value, 10, 0)
+	valid = parsed.name == "id" && err == nil
+	return
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/konveyor/tackle2-hub/shared/api"
+	"github.com/onsi/gomega"
+)
+
+func TestMetadataValidation(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	extension := func(name string, md api.Map) (ext api.Extension) {
+		ext = api.Extension{Name: name, Metadata: md}
+		return
+	}
+	fake := &FakeHub{
+		App: api.Application{
+			Resource: api.Resource{ID: 1},
+		},
+		AddonDef: api.Addon{
+			Extensions: []api.Extension{
+				extension("java", api.Map{
+					"resources": []api.Map{
+						{
+							"selector": "identity:kind=maven",
+							"fields": []api.Map{
+								{"name": "settings", "key": "maven.settings.path"},
+							},
+						},
+					},
+					"provider": api.Map{
+						"name":    "java",
+						"address": "localhost:$(PORT:-70000)",
+						"initConfig": []api.Map{
+							{
+								"location": "$(builtin.location)",
+								"providerSpecificConfig": api.Map{
+									"settings":          "$(maven.settings)",
+									"mavenSettingsFile": "$(maven.settings.path)",
+								},
+							},
+						},
+					},
+				}),
+				extension("java2", api.Map{
+					"provider": api.Map{"name": "java"},
+				}),
+				extension("unnamed", api.Map{
+					"provider": api.Map{},
+				}),
+				extension("resources", api.Map{
+					"provider": api.Map{"name": "go"},
+					"resources": []api.Map{
+						{"selector": "secret:name=x"},
+						{
+							"selector": "setting:key=a",
+							"fields": []api.Map{
+								{"name": "value", "key": "a", "type": "float"},
+								{"key": "a"},
+							},
+						},
+						{"selector": "file:name=settings.xml"},
+					},
+				}),
+				extension("required", api.Map{
+					"provider": api.Map{
+						"name": "dotnet",
+						"initConfig": []api.Map{
+							{
+								"providerSpecificConfig": api.Map{
+									"sdk": "$(sdk.path:?sdk required)",
+								},
+							},
+						},
+					},
+				}),
+			},
+		},
+	}
+	useFakeHub(t, fake)
+	mode := Mode{}
+	mode.path.appDir = "/app"
+	settings := Settings{}
+	err := settings.AppendExtensions(&mode)
+	g.Expect(errors.Is(err, &MetadataError{})).To(gomega.BeTrue())
+	var mErr *MetadataError
+	errors.As(err, &mErr)
+	var errs, warnings []string
+	for _, p := range mErr.Problems {
+		if p.Severity == SeverityError {
+			errs = append(errs, p.Description)
+		} else {
+			warnings = append(warnings, p.Description)
+		}
+	}
+	expected := []string{
+		"java: provider.address=localhost:70000 port not valid.",
+		"unnamed: provider.name must be specified.",
+		"resources: resources[0].selector=secret:name=x kind not-supported.",
+		"resources: resources[1].fields[0].type=float not-supported.",
+		"resources: resources[1].fields[1].name must be specified.",
+		"resources: resources[1].fields[1].key=a duplicated.",
+		"resources: resources[2].selector=file:name=settings.xml not-supported. expected: file:id=<id>",
+		"required: Expression: '$(sdk.path:?sdk required)' failed: key: 'sdk.path' sdk required",
+	}
+	g.Expect(len(errs)).To(gomega.Equal(len(expected)))
+	for i := range expected {
+		g.Expect(errs[i]).To(gomega.ContainSubstring(expected[i]))
+	}
+	g.Expect(len(warnings)).To(gomega.Equal(2))
+	g.Expect(warnings[0]).To(gomega.ContainSubstring("$(maven.settings) not resolved."))
+	g.Expect(warnings[1]).To(gomega.ContainSubstring("java2: provider.name=java duplicates extension: java. Ignored."))
+	// duplicate ignored (first used).
+	g.Expect(len(settings.Configs)).To(gomega.Equal(1))
+	g.Expect(settings.Configs[0].Address).To(gomega.Equal("localhost:70000"))
+}
@@ -3,6 +3,7 @@ module github.com/konveyor/tackle2-addon-analyzer
 go 1.25.12
 
 require (
+	github.com/go-logr/logr v1.4.2
 	github.com/jortel/go-utils v0.1.5
 	github.com/konveyor/analyzer-lsp v0.9.0-beta.1.0.20260318171141-1e9eb64d6989
 	github.com/konveyor/tackle2-hub/shared v0.0.0-20260317144527-dcbbf2c28635
@@ -23,7 +24,6 @@ require (
 	github.com/bufbuild/protocompile v0.10.0 // indirect
 	github.com/cbroglie/mustache v1.4.0 // indirect
 	github.com/dlclark/regexp2 v1.11.4 // indirect
-	github.com/go-logr/logr v1.4.2 // indirect
 	github.com/go-logr/stdr v1.2.2 // indirect
 	github.com/golang-jwt/jwt/v5 v5.2.2 // indirect
 	github.com/golang/groupcache v0.0.0-20210331224755-41bb18bfe9da // indirect
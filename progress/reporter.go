@@ -1,23 +1,104 @@
 package progress
 
 import (
+	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/konveyor/analyzer-lsp/progress"
-	"github.com/konveyor/tackle2-hub/shared/addon/adapter"
 )
 
+// Buffer size of the event buffer.
+const Buffer = 1024
+
+// Interval between progress reports.
+const Interval = 5 * time.Second
+
+// Addon task reporting.
+// The events are reported on the reporter goroutine so the
+// implementation must be thread-safe.
+type Addon interface {
+	Activity(entry string, v ...any)
+	Total(n int)
+	Completed(n int)
+}
+
+// AddonReporter reports analyzer progress to the addon (task).
+// Events are buffered and reported asynchronously so the analyzer is
+// not blocked by updating the task report. Events are dropped (and counted)
+// when the buffer is full.
 type AddonReporter struct {
 	events                chan progress.Event
 	closed                bool
 	droppedEvents         atomic.Uint64
-	addon                 *adapter.Adapter
+	addon                 Addon
 	lastReportedExecution *time.Time
+	lastReported          map[progress.Stage]time.Time
+	mutex                 sync.Mutex
+	done                  chan struct{}
+	stats                 Stats
 }
 
+// Report buffers the event.
 func (a *AddonReporter) Report(event progress.Event) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if a.closed {
+		a.droppedEvents.Add(1)
+		return
+	}
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+	select {
+	case a.events <- event:
+	default:
+		a.droppedEvents.Add(1)
+	}
+}
+
+// Close the reporter.
+// The buffered events are reported.
+func (a *AddonReporter) Close() {
+	a.mutex.Lock()
+	if !a.closed {
+		a.closed = true
+		close(a.events)
+	}
+	a.mutex.Unlock()
+	<-a.done
+	dropped := a.droppedEvents.Load()
+	if dropped > 0 {
+		a.addon.Activity("[ANALYZER] progress: %d events dropped.", dropped)
+	}
+}
 
+// Summary returns the progress summary.
+// Must be called after Close().
+func (a *AddonReporter) Summary() (summary Summary) {
+	summary = a.stats.Summary()
+	summary.Dropped = a.droppedEvents.Load()
+	return
+}
+
+// Rules returns the wall time of each rule (id).
+// Must be called after Close().
+func (a *AddonReporter) Rules() (m map[string]time.Duration) {
+	m = a.stats.Rules()
+	return
+}
+
+// run reports the buffered events.
+func (a *AddonReporter) run() {
+	defer close(a.done)
+	for event := range a.events {
+		a.report(event)
+	}
+}
+
+// report the event.
+func (a *AddonReporter) report(event progress.Event) {
+	stage := a.stats.Update(event)
 	switch event.Stage {
 	case progress.StageProviderInit:
 		if event.Message == "" {
@@ -30,6 +111,7 @@ func (a *AddonReporter) Report(event progress.Event) {
 		}
 	case progress.StageProviderPrepare:
 		if event.Current != 0 || event.Total != event.Current {
+			a.percent(stage, event.Message)
 			return
 		}
 		a.addon.Activity("[ANALYZER] %s", event.Message)
@@ -43,13 +125,18 @@ func (a *AddonReporter) Report(event progress.Event) {
 			a.lastReportedExecution = nil
 			return
 		}
-		if event.Current == 0 {
+		if a.lastReportedExecution == nil {
 			a.addon.Activity("[ANALYZER] starting to process %v rules", event.Total)
 			a.addon.Total(event.Total)
 			t := time.Now()
 			a.lastReportedExecution = &t
-		} else if a.lastReportedExecution == nil || time.Since(*a.lastReportedExecution) >= 5*time.Second {
-			a.addon.Activity("[ANALYZER] processed %d rules out of %d", event.Current, event.Total)
+		} else if time.Since(*a.lastReportedExecution) >= Interval {
+			a.addon.Activity(
+				"[ANALYZER] processed %d rules out of %d (%.0f%%) ETA: %s",
+				event.Current,
+				event.Total,
+				stage.Percent,
+				a.stats.ETA(stage))
 			a.addon.Completed(event.Current)
 			t := time.Now()
 			a.lastReportedExecution = &t
@@ -59,15 +146,37 @@ func (a *AddonReporter) Report(event progress.Event) {
 			return
 		}
 		a.addon.Activity("[ANALYZER] %s", event.Message)
+	default:
+		a.percent(stage, event.Message)
 	}
+}
 
+// percent reports the stage completion percentage (throttled).
+func (a *AddonReporter) percent(stage *Stage, message string) {
+	if stage.Total == 0 {
+		return
+	}
+	last, found := a.lastReported[stage.Name]
+	if found && time.Since(last) < Interval && stage.Current < stage.Total {
+		return
+	}
+	a.lastReported[stage.Name] = time.Now()
+	if message == "" {
+		message = string(stage.Name)
+	}
+	a.addon.Activity("[ANALYZER] %s: %.0f%%", message, stage.Percent)
 }
 
-func NewAddonReporter(addon *adapter.Adapter) *AddonReporter {
-	return &AddonReporter{
-		events:        make(chan progress.Event),
+// NewAddonReporter returns a started reporter.
+func NewAddonReporter(addon Addon) *AddonReporter {
+	r := &AddonReporter{
+		events:        make(chan progress.Event, Buffer),
 		closed:        false,
 		droppedEvents: atomic.Uint64{},
 		addon:         addon,
+		lastReported:  make(map[progress.Stage]time.Time),
+		done:          make(chan struct{}),
 	}
+	go r.run()
+	return r
 }
@@ -0,0 +1,216 @@
+package progress
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/konveyor/analyzer-lsp/progress"
+)
+
+// ProviderStarted prefix of the message reported when a provider started.
+const ProviderStarted = "started provider: "
+
+// Slowest number of providers (and rules) reported in the summary.
+const Slowest = 10
+
+// Stage progress.
+type Stage struct {
+	Name    progress.Stage `json:"name" yaml:"name"`
+	Current int            `json:"current,omitempty" yaml:"current,omitempty"`
+	Total   int            `json:"total,omitempty" yaml:"total,omitempty"`
+	Percent float64        `json:"percent" yaml:"percent"`
+	Started time.Time      `json:"started" yaml:"started"`
+	Updated time.Time      `json:"updated" yaml:"updated"`
+}
+
+// Duration returns the stage duration.
+func (s *Stage) Duration() (d time.Duration) {
+	d = s.Updated.Sub(s.Started)
+	return
+}
+
+// Timing of a provider (or rule).
+type Timing struct {
+	Name     string `json:"name" yaml:"name"`
+	Duration string `json:"duration" yaml:"duration"`
+	//
+	duration time.Duration
+}
+
+// Summary progress summary.
+type Summary struct {
+	Stages    []StageSummary `json:"stages" yaml:"stages"`
+	Providers []Timing       `json:"providers" yaml:"providers"`
+	Rules     []Timing       `json:"rules" yaml:"rules"`
+	Dropped   uint64         `json:"dropped" yaml:"dropped"`
+}
+
+// StageSummary stage summary.
+type StageSummary struct {
+	Name     progress.Stage `json:"name" yaml:"name"`
+	Percent  float64        `json:"percent" yaml:"percent"`
+	Duration string         `json:"duration" yaml:"duration"`
+}
+
+// Stats progress statistics.
+// The duration of a provider is the time to initialize plus the time to prepare.
+// Only the completion of a rule (by rule id without the ruleset) is reported.
+// The wall time of a rule is the time since the previous rule completed.
+// Tagging rules are executed sequentially so the time is exact. Other rules
+// are executed concurrently so the time is an approximation attributed to
+// the rule that completed.
+type Stats struct {
+	stages    map[progress.Stage]*Stage
+	order     []progress.Stage
+	initStart time.Time
+	providers map[string]time.Duration
+	prepare   map[string]*Stage
+	rules     map[string]time.Duration
+	ruleMark  time.Time
+}
+
+// Update the statistics with the event.
+func (r *Stats) Update(event progress.Event) (stage *Stage) {
+	if r.stages == nil {
+		r.stages = make(map[progress.Stage]*Stage)
+		r.providers = make(map[string]time.Duration)
+		r.prepare = make(map[string]*Stage)
+		r.rules = make(map[string]time.Duration)
+	}
+	event.Normalize()
+	stage, found := r.stages[event.Stage]
+	if !found {
+		stage = &Stage{
+			Name:    event.Stage,
+			Started: event.Timestamp,
+		}
+		r.stages[event.Stage] = stage
+		r.order = append(r.order, event.Stage)
+	}
+	stage.Updated = event.Timestamp
+	if event.Total > 0 {
+		stage.Current = event.Current
+		stage.Total = event.Total
+		stage.Percent = event.Percent
+	}
+	switch event.Stage {
+	case progress.StageProviderInit:
+		r.providerInit(event)
+	case progress.StageProviderPrepare:
+		r.providerPrepare(event)
+	case progress.StageRuleExecution:
+		r.ruleExecution(event, !found)
+	}
+	return
+}
+
+// Rules returns the wall time of each rule (id).
+func (r *Stats) Rules() (m map[string]time.Duration) {
+	m = make(map[string]time.Duration)
+	for id, d := range r.rules {
+		m[id] = d
+	}
+	return
+}
+
+// ETA returns the estimated time remaining for the stage.
+// Estimated using the average time (in the stage) to complete a rule.
+func (r *Stats) ETA(stage *Stage) (d time.Duration) {
+	if stage.Current == 0 || stage.Current >= stage.Total {
+		return
+	}
+	average := stage.Duration() / time.Duration(stage.Current)
+	d = average * time.Duration(stage.Total-stage.Current)
+	d = d.Round(time.Second)
+	return
+}
+
+// Summary returns the summary.
+func (r *Stats) Summary() (summary Summary) {
+	for _, name := range r.order {
+		stage := r.stages[name]
+		summary.Stages = append(
+			summary.Stages,
+			StageSummary{
+				Name:     stage.Name,
+				Percent:  stage.Percent,
+				Duration: stage.Duration().String(),
+			})
+	}
+	providers := make(map[string]time.Duration)
+	for name, d := range r.providers {
+		providers[name] = d
+	}
+	for name, stage := range r.prepare {
+		providers[name] += stage.Duration()
+	}
+	summary.Providers = r.slowest(providers)
+	summary.Rules = r.slowest(r.rules)
+	return
+}
+
+// providerInit records the time to initialize each provider.
+func (r *Stats) providerInit(event progress.Event) {
+	name, found := strings.CutPrefix(event.Message, ProviderStarted)
+	if !found {
+		if r.initStart.IsZero() {
+			r.initStart = event.Timestamp
+		}
+		return
+	}
+	if r.initStart.IsZero() {
+		return
+	}
+	r.providers[name] = event.Timestamp.Sub(r.initStart)
+}
+
+// providerPrepare records the time to prepare each provider.
+func (r *Stats) providerPrepare(event progress.Event) {
+	name, cast := event.Metadata["providerName"].(string)
+	if !cast || name == "" {
+		return
+	}
+	stage, found := r.prepare[name]
+	if !found {
+		stage = &Stage{Name: event.Stage, Started: event.Timestamp}
+		r.prepare[name] = stage
+	}
+	stage.Updated = event.Timestamp
+}
+
+// ruleExecution records the wall time of the rule (id) reported
+// in the message when completed. The first event starts the stage.
+func (r *Stats) ruleExecution(event progress.Event, started bool) {
+	if started || event.Message == "" {
+		r.ruleMark = event.Timestamp
+		return
+	}
+	r.rules[event.Message] += event.Timestamp.Sub(r.ruleMark)
+	r.ruleMark = event.Timestamp
+}
+
+// slowest returns the slowest (sorted) timings.
+func (r *Stats) slowest(m map[string]time.Duration) (list []Timing) {
+	for name, d := range m {
+		list = append(
+			list,
+			Timing{
+				Name:     name,
+				Duration: d.String(),
+				duration: d,
+			})
+	}
+	sort.Slice(
+		list,
+		func(i, j int) bool {
+			if list[i].duration != list[j].duration {
+				return list[i].duration > list[j].duration
+			}
+			return list[i].Name < list[j].Name
+		})
+	if len(list) > Slowest {
+		list = list[:Slowest]
+	}
+	return
+}
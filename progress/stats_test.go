@@ -0,0 +1,52 @@
+package progress
+
+import (
+	"testing"
+	"time"
+
+	"github.com/konveyor/analyzer-lsp/progress"
+	"github.com/onsi/gomega"
+)
+
+func TestStats(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	mark := time.Now()
+	at := func(seconds int) (t time.Time) {
+		t = mark.Add(time.Duration(seconds) * time.Second)
+		return
+	}
+	stats := Stats{}
+	// providers.
+	stats.Update(progress.Event{Timestamp: at(0), Stage: progress.StageProviderInit, Message: "Starting provider init"})
+	stats.Update(progress.Event{Timestamp: at(2), Stage: progress.StageProviderInit, Message: "started provider: builtin"})
+	stats.Update(progress.Event{Timestamp: at(8), Stage: progress.StageProviderInit, Message: "started provider: java"})
+	java := map[string]any{"providerName": "java"}
+	stats.Update(progress.Event{Timestamp: at(8), Stage: progress.StageProviderPrepare, Total: 10, Metadata: java})
+	stage := stats.Update(progress.Event{Timestamp: at(10), Stage: progress.StageProviderPrepare, Current: 5, Total: 10, Metadata: java})
+	g.Expect(stage.Percent).To(gomega.Equal(50.0))
+	// rules.
+	stats.Update(progress.Event{Timestamp: at(10), Stage: progress.StageRuleExecution, Total: 4, Message: "Starting"})
+	stats.Update(progress.Event{Timestamp: at(11), Stage: progress.StageRuleExecution, Current: 1, Total: 4, Message: "rule-001"})
+	stage = stats.Update(progress.Event{Timestamp: at(14), Stage: progress.StageRuleExecution, Current: 2, Total: 4, Message: "rule-002"})
+	g.Expect(stage.Percent).To(gomega.Equal(50.0))
+	g.Expect(stats.ETA(stage)).To(gomega.Equal(4 * time.Second))
+	stats.Update(progress.Event{Timestamp: at(15), Stage: progress.StageRuleExecution, Current: 3, Total: 4, Message: "rule-003"})
+	stage = stats.Update(progress.Event{Timestamp: at(20), Stage: progress.StageRuleExecution, Current: 4, Total: 4, Message: "rule-004"})
+	g.Expect(stats.ETA(stage)).To(gomega.Equal(time.Duration(0)))
+	// summary.
+	summary := stats.Summary()
+	g.Expect(len(summary.Stages)).To(gomega.Equal(3))
+	g.Expect(summary.Stages[2].Name).To(gomega.Equal(progress.StageRuleExecution))
+	g.Expect(summary.Stages[2].Percent).To(gomega.Equal(100.0))
+	g.Expect(summary.Stages[2].Duration).To(gomega.Equal("10s"))
+	g.Expect(len(summary.Providers)).To(gomega.Equal(2))
+	g.Expect(summary.Providers[0].Name).To(gomega.Equal("java"))
+	g.Expect(summary.Providers[0].Duration).To(gomega.Equal("10s"))
+	// rules.
+	g.Expect(len(summary.Rules)).To(gomega.Equal(4))
+	g.Expect(summary.Rules[0].Name).To(gomega.Equal("rule-004"))
+	g.Expect(summary.Rules[0].Duration).To(gomega.Equal("5s"))
+	g.Expect(summary.Rules[1].Name).To(gomega.Equal("rule-002"))
+	g.Expect(summary.Rules[1].Duration).To(gomega.Equal("3s"))
+	g.Expect(stats.Rules()["rule-001"]).To(gomega.Equal(time.Second))
+}